@@ -0,0 +1,89 @@
+package appjson
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// backoffTypes are the supported schedules for the wait between attempts
+var backoffTypes = []string{"exponential", "fixed"}
+
+func (h Healthcheck) GetBackoff() string {
+	if h.Backoff == "" {
+		return "fixed"
+	}
+
+	return h.Backoff
+}
+
+func (h Healthcheck) validateBackoff() error {
+	if !slices.Contains(backoffTypes, h.GetBackoff()) {
+		return fmt.Errorf("healthcheck name='%s' has an invalid 'backoff' value: must be one of %s", h.GetName(), strings.Join(backoffTypes, ", "))
+	}
+
+	if h.MaxWait < 0 {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a negative 'maxWait' value", h.GetName())
+	}
+
+	if h.MaxWait > 0 && h.GetBackoff() != "exponential" {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a 'maxWait' value without an 'exponential' backoff", h.GetName())
+	}
+
+	if h.Jitter < 0 {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a negative 'jitter' value", h.GetName())
+	}
+
+	return nil
+}
+
+// retryDelay returns the wait after the given failed attempt, counted from
+// zero, before any jitter is added. An exponential backoff doubles the wait
+// after every attempt, up to the maxWait when one is set.
+func (h Healthcheck) retryDelay(attempt uint) time.Duration {
+	wait := time.Duration(h.GetWait()) * time.Second
+	if h.GetBackoff() != "exponential" {
+		return wait
+	}
+
+	maxWait := time.Duration(h.MaxWait) * time.Second
+	for range attempt {
+		if maxWait > 0 && wait >= maxWait {
+			break
+		}
+
+		// stop doubling well before the duration could overflow
+		if wait > math.MaxInt64/2 {
+			break
+		}
+		wait *= 2
+	}
+
+	if maxWait > 0 && wait > maxWait {
+		return maxWait
+	}
+
+	return wait
+}
+
+// retryWaitDuration returns the worst-case total wait between all attempts
+func (h Healthcheck) retryWaitDuration() time.Duration {
+	var total time.Duration
+	for attempt := range uint(max(h.GetAttempts()-1, 0)) {
+		total += h.retryDelay(attempt) + time.Duration(h.Jitter)*time.Second
+	}
+
+	return total
+}
+
+// retryJitter returns a random delay of up to jitter seconds
+func (h Healthcheck) retryJitter() time.Duration {
+	if h.Jitter <= 0 {
+		return 0
+	}
+
+	return rand.N(time.Duration(h.Jitter) * time.Second)
+}
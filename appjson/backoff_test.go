@@ -0,0 +1,53 @@
+package appjson
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthcheck_retryDelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthcheck Healthcheck
+		want        []time.Duration
+	}{
+		{
+			name:        "when using a fixed backoff",
+			healthcheck: Healthcheck{Wait: 3},
+			want:        []time.Duration{3 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:        "when using an exponential backoff",
+			healthcheck: Healthcheck{Wait: 2, Backoff: "exponential"},
+			want:        []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second},
+		},
+		{
+			name:        "when capping an exponential backoff",
+			healthcheck: Healthcheck{Wait: 2, Backoff: "exponential", MaxWait: 10},
+			want:        []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt, want := range tt.want {
+				if got := tt.healthcheck.retryDelay(uint(attempt)); got != want {
+					t.Errorf("Healthcheck.retryDelay(%d) = %s, want %s", attempt, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthcheck_retryDelayOverflow(t *testing.T) {
+	h := Healthcheck{Wait: 5, Backoff: "exponential"}
+	if got := h.retryDelay(200); got <= 0 {
+		t.Errorf("Healthcheck.retryDelay(200) = %s, want a positive duration", got)
+	}
+}
+
+func TestHealthcheck_retryWaitDuration(t *testing.T) {
+	h := Healthcheck{Attempts: 4, Wait: 2, Backoff: "exponential", MaxWait: 5, Jitter: 1}
+	if got, want := h.retryWaitDuration(), 14*time.Second; got != want {
+		t.Errorf("Healthcheck.retryWaitDuration() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package appjson
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// Checker implements a single check strategy
+type Checker interface {
+	// Configured returns whether the healthcheck fields select the strategy.
+	// Strategies that are only selected via the 'strategy' field return false.
+	Configured(h Healthcheck) bool
+
+	// Validate returns an error when the healthcheck is invalid for the strategy
+	Validate(h Healthcheck) error
+
+	// Describe returns the key=value pairs logged when the healthcheck runs
+	Describe(h Healthcheck, container container_types.InspectResponse) string
+
+	// Execute runs a single attempt of the healthcheck against the container.
+	// The context is done once the attempt timeout passes, and an error wrapped
+	// with Unrecoverable stops the healthcheck without further attempts.
+	Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error)
+}
+
+// startDelayer is implemented by strategies that wait before their first
+// attempt, such as for the container to reach an uptime. It is passed
+// how long the container has been running for.
+type startDelayer interface {
+	startDelay(h Healthcheck, uptime time.Duration) time.Duration
+}
+
+// attemptTimeouter is implemented by strategies whose attempts are
+// made of several steps that are each limited by the timeout
+type attemptTimeouter interface {
+	attemptTimeout(h Healthcheck) time.Duration
+}
+
+// unselectedValidator is implemented by strategies with fields that
+// are only valid on a healthcheck that selects the strategy
+type unselectedValidator interface {
+	validateUnselected(h Healthcheck) error
+}
+
+var (
+	checkersMu sync.RWMutex
+
+	// checkers holds every registered strategy by name
+	checkers = map[CheckType]Checker{}
+
+	// checkerOrder holds the strategy names in the order they were registered,
+	// which is the order in which the healthcheck fields are matched
+	checkerOrder = []CheckType{}
+)
+
+func init() {
+	RegisterChecker(ListeningCheck, listeningChecker{})
+	RegisterChecker(CommandCheck, commandChecker{})
+	RegisterChecker(PathCheck, pathChecker{})
+	RegisterChecker(DockerHealthCheck, dockerHealthChecker{})
+	RegisterChecker(LogsCheck, logsChecker{})
+	RegisterChecker(ResourcesCheck, resourcesChecker{})
+	RegisterChecker(ProcessCheck, processChecker{})
+	RegisterChecker(ZombiesCheck, zombiesChecker{})
+	RegisterChecker(FileCheck, fileChecker{})
+	RegisterChecker(EnvCheck, envChecker{})
+	RegisterChecker(HostCommandCheck, hostCommandChecker{})
+	RegisterChecker(UptimeCheck, uptimeChecker{})
+}
+
+// withDockerClient runs fn with the shared docker client of the context,
+// or with a client created for the attempt and closed once fn returns
+func withDockerClient(hctx HealthcheckContext, fn func(cli *client.Client) ([]byte, error)) ([]byte, error) {
+	if hctx.Client != nil {
+		return fn(hctx.Client)
+	}
+
+	cli, err := client.NewClientWithOpts(
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return []byte{}, Unrecoverable(err)
+	}
+	defer cli.Close()
+
+	return fn(cli)
+}
+
+// RegisterChecker makes a check strategy available by name, panicking
+// if the checker is nil or the name is already registered
+func RegisterChecker(name CheckType, checker Checker) {
+	checkersMu.Lock()
+	defer checkersMu.Unlock()
+
+	if checker == nil {
+		panic("appjson: RegisterChecker checker is nil")
+	}
+
+	if _, ok := checkers[name]; ok {
+		panic(fmt.Sprintf("appjson: RegisterChecker called twice for strategy '%s'", name))
+	}
+
+	checkers[name] = checker
+	checkerOrder = append(checkerOrder, name)
+}
+
+// GetChecker returns the check strategy registered under name
+func GetChecker(name CheckType) (Checker, bool) {
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+
+	checker, ok := checkers[name]
+	return checker, ok
+}
+
+// CheckerNames returns the name of every registered check strategy in registration order
+func CheckerNames() []CheckType {
+	checkersMu.RLock()
+	defer checkersMu.RUnlock()
+
+	return append([]CheckType{}, checkerOrder...)
+}
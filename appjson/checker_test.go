@@ -0,0 +1,97 @@
+package appjson
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	container_types "github.com/moby/moby/api/types/container"
+)
+
+type staticChecker struct{}
+
+func (staticChecker) Configured(h Healthcheck) bool {
+	return false
+}
+
+func (staticChecker) Validate(h Healthcheck) error {
+	return nil
+}
+
+func (staticChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return "type='static'"
+}
+
+func (staticChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return h.Options, nil
+}
+
+func TestRegisterChecker(t *testing.T) {
+	RegisterChecker("static", staticChecker{})
+
+	if _, ok := GetChecker("static"); !ok {
+		t.Fatalf("GetChecker() did not return the registered checker")
+	}
+
+	h := Healthcheck{Name: "custom", Strategy: "static", Options: json.RawMessage(`{"key":"value"}`)}
+	if h.GetCheckType() != "static" {
+		t.Errorf("Healthcheck.GetCheckType() = %s, want static", h.GetCheckType())
+	}
+
+	b, errs := h.Execute(context.Background(), container_types.InspectResponse{}, HealthcheckContext{})
+	if len(errs) > 0 || string(b) != `{"key":"value"}` {
+		t.Errorf("Healthcheck.Execute() = %s, %v", string(b), errs)
+	}
+
+	if err := (Healthcheck{Strategy: "static", Uptime: 10}).Validate(); err == nil {
+		t.Errorf("Healthcheck.Validate() expected error when a strategy and uptime are both set")
+	}
+
+	if err := (Healthcheck{Strategy: "missing"}).Validate(); err == nil {
+		t.Errorf("Healthcheck.Validate() expected error for an unknown strategy")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterChecker() expected a panic when registering a strategy twice")
+		}
+	}()
+	RegisterChecker("static", staticChecker{})
+}
+
+func TestHealthcheck_Describe(t *testing.T) {
+	container := container_types.InspectResponse{Config: &container_types.Config{}}
+	tests := []struct {
+		name        string
+		healthcheck Healthcheck
+		want        string
+	}{
+		{
+			name:        "when the healthcheck is a command check",
+			healthcheck: Healthcheck{Name: "check", Command: []string{"echo", "hi"}},
+			want:        "name='check' attempts=3 command='[echo hi]' timeout=5 type='command' wait=5 execWrapper='none'",
+		},
+		{
+			name:        "when the healthcheck is a path check",
+			healthcheck: Healthcheck{Name: "check", Path: "/health"},
+			want:        "name='check' delay=0 path='/health' retries=2 timeout=5 type='path'",
+		},
+		{
+			name:        "when the healthcheck has no strategy",
+			healthcheck: Healthcheck{Name: "check"},
+			want:        "name='check' type='uptime' uptime=0",
+		},
+		{
+			name:        "when the healthcheck is an env check",
+			healthcheck: Healthcheck{Name: "check", EnvAssertions: []EnvAssertion{{Name: "PORT"}}},
+			want:        "name='check' envAssertions=1 type='env'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.healthcheck.Describe(container); got != tt.want {
+				t.Errorf("Healthcheck.Describe() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
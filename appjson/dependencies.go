@@ -0,0 +1,170 @@
+package appjson
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ValidateDependencies returns an error when a healthcheck depends on a
+// healthcheck that is not part of the same run, on a name shared by more
+// than one healthcheck, or on itself through a dependency cycle
+func ValidateDependencies(healthchecks []Healthcheck) error {
+	counts := map[string]int{}
+	for _, h := range healthchecks {
+		counts[h.GetName()]++
+	}
+
+	for _, h := range healthchecks {
+		for _, dependency := range h.DependsOn {
+			switch counts[dependency] {
+			case 0:
+				return fmt.Errorf("healthcheck name='%s' depends on unknown healthcheck name='%s'", h.GetName(), dependency)
+			case 1:
+			default:
+				return fmt.Errorf("healthcheck name='%s' depends on name='%s', which is used by %d healthchecks", h.GetName(), dependency, counts[dependency])
+			}
+		}
+	}
+
+	dependencies := map[string][]string{}
+	for _, h := range healthchecks {
+		dependencies[h.GetName()] = h.DependsOn
+	}
+
+	// visiting holds the names on the current path, visited the names already known to be acyclic
+	visiting := map[string]bool{}
+	visited := map[string]bool{}
+	path := []string{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("healthcheck dependency cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+
+		visiting[name] = true
+		path = append(path, name)
+		for _, dependency := range dependencies[name] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		visiting[name] = false
+		visited[name] = true
+
+		return nil
+	}
+
+	for _, h := range healthchecks {
+		if err := visit(h.GetName()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MaxDuration returns the worst-case time to run the healthchecks, where a
+// healthcheck only starts once its dependencies have finished and its initial
+// delay has passed. When concurrency is above zero, at most that many
+// healthchecks run at once, with a free slot going to the first declared
+// healthcheck that is ready. The dependencies must have been validated with
+// ValidateDependencies.
+func MaxDuration(healthchecks []Healthcheck, concurrency int) time.Duration {
+	indexes := map[string]int{}
+	for i, h := range healthchecks {
+		indexes[h.GetName()] = i
+	}
+
+	if concurrency > 0 {
+		return scheduledDuration(healthchecks, indexes, concurrency)
+	}
+
+	finished := map[int]time.Duration{}
+	var finish func(i int) time.Duration
+	finish = func(i int) time.Duration {
+		if d, ok := finished[i]; ok {
+			return d
+		}
+
+		h := healthchecks[i]
+		delay := time.Duration(h.GetInitialDelay()) * time.Second
+		start := delay
+		for _, dependency := range h.DependsOn {
+			start = max(start, finish(indexes[dependency]))
+		}
+
+		finished[i] = start + h.GetMaxDuration() - delay
+		return finished[i]
+	}
+
+	var worstCase time.Duration
+	for i := range healthchecks {
+		worstCase = max(worstCase, finish(i))
+	}
+
+	return worstCase
+}
+
+// scheduledDuration simulates running the healthcheck graph with a limited
+// number of slots. A healthcheck holds its slot while waiting out its initial
+// delay, so the full worst-case duration of each healthcheck is used.
+func scheduledDuration(healthchecks []Healthcheck, indexes map[string]int, concurrency int) time.Duration {
+	finished := map[int]time.Duration{}
+	running := map[int]time.Duration{}
+	started := map[int]bool{}
+
+	var now time.Duration
+	for len(finished) < len(healthchecks) {
+		for i, h := range healthchecks {
+			if len(running) >= concurrency {
+				break
+			}
+			if started[i] {
+				continue
+			}
+
+			ready := true
+			for _, dependency := range h.DependsOn {
+				if _, ok := finished[indexes[dependency]]; !ok {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				started[i] = true
+				running[i] = now + h.GetMaxDuration()
+			}
+		}
+
+		next := time.Duration(-1)
+		for _, end := range running {
+			if next < 0 || end < next {
+				next = end
+			}
+		}
+		now = next
+
+		for i, end := range running {
+			if end == now {
+				finished[i] = end
+				delete(running, i)
+			}
+		}
+	}
+
+	return now
+}
@@ -0,0 +1,139 @@
+package appjson
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name         string
+		healthchecks []Healthcheck
+		wantErr      string
+	}{
+		{
+			name: "when dependencies form a graph",
+			healthchecks: []Healthcheck{
+				{Name: "listening", Listening: true},
+				{Name: "shallow", Path: "/health", DependsOn: []string{"listening"}},
+				{Name: "deep", Path: "/health/db", DependsOn: []string{"listening", "shallow"}},
+			},
+		},
+		{
+			name: "when a dependency is unknown",
+			healthchecks: []Healthcheck{
+				{Name: "shallow", Path: "/health", DependsOn: []string{"listening"}},
+			},
+			wantErr: "healthcheck name='shallow' depends on unknown healthcheck name='listening'",
+		},
+		{
+			name: "when a dependency name is ambiguous",
+			healthchecks: []Healthcheck{
+				{Name: "listening", Listening: true},
+				{Name: "listening", Uptime: 10},
+				{Name: "shallow", Path: "/health", DependsOn: []string{"listening"}},
+			},
+			wantErr: "healthcheck name='shallow' depends on name='listening', which is used by 2 healthchecks",
+		},
+		{
+			name: "when a healthcheck depends on itself",
+			healthchecks: []Healthcheck{
+				{Name: "shallow", Path: "/health", DependsOn: []string{"shallow"}},
+			},
+			wantErr: "healthcheck dependency cycle detected: shallow -> shallow",
+		},
+		{
+			name: "when dependencies form a cycle",
+			healthchecks: []Healthcheck{
+				{Name: "listening", Listening: true, DependsOn: []string{"deep"}},
+				{Name: "shallow", Path: "/health", DependsOn: []string{"listening"}},
+				{Name: "deep", Path: "/health/db", DependsOn: []string{"shallow"}},
+			},
+			wantErr: "healthcheck dependency cycle detected: listening -> deep -> shallow -> listening",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDependencies(tt.healthchecks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateDependencies() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateDependencies() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxDuration(t *testing.T) {
+	tests := []struct {
+		name         string
+		healthchecks []Healthcheck
+		concurrency  int
+		want         time.Duration
+	}{
+		{
+			name: "when healthchecks are independent",
+			healthchecks: []Healthcheck{
+				{Name: "uptime", Uptime: 10, Attempts: 1},
+				{Name: "command", Command: []string{"true"}, Attempts: 1, Timeout: 30},
+			},
+			want: 30 * time.Second,
+		},
+		{
+			name: "when a resources healthcheck has no resources value",
+			healthchecks: []Healthcheck{
+				{Name: "resources", Strategy: "resources", Attempts: 1, Timeout: 5},
+			},
+			want: 5 * time.Second,
+		},
+		{
+			name: "when healthchecks depend on each other",
+			healthchecks: []Healthcheck{
+				{Name: "listening", Listening: true, Attempts: 1, Timeout: 10},
+				{Name: "shallow", Command: []string{"true"}, Attempts: 1, Timeout: 5, DependsOn: []string{"listening"}},
+				{Name: "deep", Command: []string{"true"}, Attempts: 1, Timeout: 5, DependsOn: []string{"shallow"}},
+			},
+			want: 20 * time.Second,
+		},
+		{
+			name: "when the initial delay outlasts the dependencies",
+			healthchecks: []Healthcheck{
+				{Name: "listening", Listening: true, Attempts: 1, Timeout: 10},
+				{Name: "shallow", Command: []string{"true"}, Attempts: 1, Timeout: 5, InitialDelay: 30, DependsOn: []string{"listening"}},
+			},
+			want: 35 * time.Second,
+		},
+		{
+			name: "when concurrency is limited",
+			healthchecks: []Healthcheck{
+				{Name: "first", Command: []string{"true"}, Attempts: 1, Timeout: 10},
+				{Name: "second", Command: []string{"true"}, Attempts: 1, Timeout: 5},
+				{Name: "third", Command: []string{"true"}, Attempts: 1, Timeout: 5},
+			},
+			concurrency: 2,
+			want:        10 * time.Second,
+		},
+		{
+			name: "when concurrency is limited to a single check",
+			healthchecks: []Healthcheck{
+				{Name: "listening", Listening: true, Attempts: 1, Timeout: 10},
+				{Name: "shallow", Command: []string{"true"}, Attempts: 1, Timeout: 5, DependsOn: []string{"listening"}},
+				{Name: "uptime", Uptime: 10, Attempts: 1},
+			},
+			concurrency: 1,
+			want:        30 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaxDuration(tt.healthchecks, tt.concurrency); got != tt.want {
+				t.Errorf("MaxDuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
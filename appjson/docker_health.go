@@ -0,0 +1,82 @@
+package appjson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// dockerHealthLogEntries is the number of State.Health.Log entries included in the check output
+const dockerHealthLogEntries = 3
+
+type dockerHealthChecker struct{}
+
+func (dockerHealthChecker) Configured(h Healthcheck) bool {
+	return h.DockerHealth
+}
+
+func (dockerHealthChecker) Validate(h Healthcheck) error {
+	return nil
+}
+
+func (dockerHealthChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("attempts=%d timeout=%d type='dockerHealth' wait=%d", h.GetAttempts(), h.GetTimeout(), h.GetWait())
+}
+
+func (dockerHealthChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	if container.State == nil || container.State.Health == nil {
+		return []byte{}, Unrecoverable(errors.New("container does not define a docker HEALTHCHECK"))
+	}
+
+	return withDockerClient(hctx, func(cli *client.Client) ([]byte, error) {
+		return h.dockerHealthCheck(ctx, cli, container)
+	})
+}
+
+func (h Healthcheck) dockerHealthCheck(ctx context.Context, cli *client.Client, container container_types.InspectResponse) ([]byte, error) {
+	inspect, err := cli.ContainerInspect(ctx, container.ID, client.ContainerInspectOptions{})
+	if err != nil {
+		return []byte{}, err
+	}
+
+	state := inspect.Container.State
+	if state == nil || state.Health == nil {
+		return []byte{}, Unrecoverable(errors.New("container does not define a docker HEALTHCHECK"))
+	}
+
+	b := dockerHealthOutput(state.Health)
+	switch state.Health.Status {
+	case container_types.Healthy:
+		return b, nil
+	case container_types.Unhealthy:
+		return b, Unrecoverable(fmt.Errorf("container health status is unhealthy after %d consecutive failures", state.Health.FailingStreak))
+	default:
+		return b, fmt.Errorf("container health status is %s", state.Health.Status)
+	}
+}
+
+func dockerHealthOutput(health *container_types.Health) []byte {
+	entries := health.Log
+	if len(entries) > dockerHealthLogEntries {
+		entries = entries[len(entries)-dockerHealthLogEntries:]
+	}
+
+	lines := []string{}
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("start=%s exit_code=%d", entry.Start.Format(time.RFC3339), entry.ExitCode))
+		for _, line := range strings.Split(strings.TrimSpace(entry.Output), "\n") {
+			lines = append(lines, fmt.Sprintf("  %s", line))
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
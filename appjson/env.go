@@ -0,0 +1,114 @@
+package appjson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	container_types "github.com/moby/moby/api/types/container"
+)
+
+type EnvAssertion struct {
+	Absent   bool   `json:"absent,omitempty"`
+	Name     string `json:"name,omitempty"`
+	NonEmpty bool   `json:"nonEmpty,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+}
+
+func (e EnvAssertion) Validate() error {
+	if e.Name == "" {
+		return errors.New("missing 'name' value")
+	}
+
+	if e.Absent && (e.NonEmpty || e.Pattern != "") {
+		return fmt.Errorf("environment variable '%s' cannot be asserted as absent while also asserting 'nonEmpty' or 'pattern'", e.Name)
+	}
+
+	if e.Pattern != "" {
+		if _, err := regexp.Compile(e.Pattern); err != nil {
+			return fmt.Errorf("invalid 'pattern' value for environment variable '%s': %w", e.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Assert checks the assertion against the container environment, never
+// including the variable value in the returned summary or error
+func (e EnvAssertion) Assert(env map[string]string) (string, error) {
+	value, present := env[e.Name]
+	if e.Absent {
+		if present {
+			return fmt.Sprintf("%s=<redacted>", e.Name), fmt.Errorf("environment variable '%s' is set", e.Name)
+		}
+		return fmt.Sprintf("%s is absent", e.Name), nil
+	}
+
+	if !present {
+		return fmt.Sprintf("%s is absent", e.Name), fmt.Errorf("environment variable '%s' is not set", e.Name)
+	}
+
+	summary := fmt.Sprintf("%s=<redacted>", e.Name)
+	if e.NonEmpty && value == "" {
+		return summary, fmt.Errorf("environment variable '%s' is empty", e.Name)
+	}
+
+	if e.Pattern != "" && !regexp.MustCompile(e.Pattern).MatchString(value) {
+		return summary, fmt.Errorf("environment variable '%s' does not match pattern: %s", e.Name, e.Pattern)
+	}
+
+	return summary, nil
+}
+
+type envChecker struct{}
+
+func (envChecker) Configured(h Healthcheck) bool {
+	return len(h.EnvAssertions) > 0
+}
+
+func (envChecker) Validate(h Healthcheck) error {
+	if len(h.EnvAssertions) == 0 {
+		return fmt.Errorf("healthcheck name='%s' requires a 'envAssertions' value for the 'env' strategy", h.GetName())
+	}
+
+	for _, assertion := range h.EnvAssertions {
+		if err := assertion.Validate(); err != nil {
+			return fmt.Errorf("healthcheck name='%s' has an invalid 'envAssertions' value: %w", h.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (envChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("envAssertions=%d type='env'", len(h.EnvAssertions))
+}
+
+func (envChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	env := map[string]string{}
+	if container.Config != nil {
+		for _, entry := range container.Config.Env {
+			name, value, _ := strings.Cut(entry, "=")
+			env[name] = value
+		}
+	}
+
+	lines := []string{}
+	failures := []string{}
+	for _, assertion := range h.EnvAssertions {
+		line, err := assertion.Assert(env)
+		lines = append(lines, line)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	b := []byte(strings.Join(lines, "\n"))
+	if len(failures) > 0 {
+		return b, Unrecoverable(errors.New(strings.Join(failures, ", ")))
+	}
+
+	return b, nil
+}
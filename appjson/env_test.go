@@ -0,0 +1,63 @@
+package appjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvAssertion_Assert(t *testing.T) {
+	env := map[string]string{
+		"DATABASE_URL": "postgres://user:secret@db:5432/app",
+		"EMPTY":        "",
+		"RAILS_ENV":    "development",
+	}
+
+	tests := []struct {
+		name      string
+		assertion EnvAssertion
+		wantErr   bool
+	}{
+		{
+			name:      "when a required variable is present",
+			assertion: EnvAssertion{Name: "DATABASE_URL", NonEmpty: true},
+			wantErr:   false,
+		},
+		{
+			name:      "when a required variable is missing",
+			assertion: EnvAssertion{Name: "REDIS_URL"},
+			wantErr:   true,
+		},
+		{
+			name:      "when a non-empty variable is empty",
+			assertion: EnvAssertion{Name: "EMPTY", NonEmpty: true},
+			wantErr:   true,
+		},
+		{
+			name:      "when a variable does not match the pattern",
+			assertion: EnvAssertion{Name: "RAILS_ENV", Pattern: "^production$"},
+			wantErr:   true,
+		},
+		{
+			name:      "when an absent variable is set",
+			assertion: EnvAssertion{Name: "DATABASE_URL", Absent: true},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := tt.assertion.Assert(env)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EnvAssertion.Assert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for _, value := range env {
+				if value == "" {
+					continue
+				}
+				if strings.Contains(summary, value) || (err != nil && strings.Contains(err.Error(), value)) {
+					t.Errorf("EnvAssertion.Assert() leaked a variable value: summary=%s err=%v", summary, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package appjson
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/client"
+)
+
+// execWrapper prepares a command check so it runs with the same
+// environment the container entrypoint would have set up
+type execWrapper struct {
+	// detect returns whether the wrapper applies to the container config
+	detect func(config *container_types.Config) bool
+
+	// wrap returns the command to exec in the container
+	wrap func(ctx context.Context, cli *client.Client, container container_types.InspectResponse, command []string) (execCommand, error)
+}
+
+// execCommand is a command check prepared by an exec wrapper
+type execCommand struct {
+	// Cmd is the command to exec in the container
+	Cmd []string
+
+	// Cleanup is an optional command run in the container once Cmd completes
+	Cleanup []string
+
+	// Stdin is optional input written to Cmd
+	Stdin []byte
+}
+
+// execWrapperOrder is the order in which exec wrappers are detected,
+// with the first matching wrapper being used for a container
+var execWrapperOrder = []string{"cnb", "init", "shell", "entrypoint", "herokuish"}
+
+var execWrappers = map[string]execWrapper{
+	"cnb": {
+		detect: isCNBContainer,
+		wrap: func(ctx context.Context, cli *client.Client, container container_types.InspectResponse, command []string) (execCommand, error) {
+			return execCommand{Cmd: append([]string{"/cnb/lifecycle/launcher", "--"}, command...)}, nil
+		},
+	},
+	"entrypoint": {
+		detect: func(config *container_types.Config) bool {
+			return len(config.Entrypoint) > 0 && !reflect.DeepEqual(config.Entrypoint, containerShell(config)) && !isShellEntrypoint(config.Entrypoint)
+		},
+		wrap: func(ctx context.Context, cli *client.Client, container container_types.InspectResponse, command []string) (execCommand, error) {
+			return execCommand{Cmd: append(slices.Clone(container.Config.Entrypoint), command...)}, nil
+		},
+	},
+	"herokuish": {
+		detect: func(config *container_types.Config) bool {
+			return config.Labels["com.gliderlabs.herokuish/stack"] != ""
+		},
+		wrap: wrapHerokuishCommand,
+	},
+	"init": {
+		detect: func(config *container_types.Config) bool {
+			return len(config.Entrypoint) > 0 && isInitBinary(config.Entrypoint[0])
+		},
+		wrap: func(ctx context.Context, cli *client.Client, container container_types.InspectResponse, command []string) (execCommand, error) {
+			// the init process only reaps children as pid 1, so the
+			// command runs under whatever entrypoint it would launch
+			return execCommand{Cmd: append(stripInitEntrypoint(container.Config), command...)}, nil
+		},
+	},
+	"none": {
+		detect: func(config *container_types.Config) bool {
+			return true
+		},
+		wrap: func(ctx context.Context, cli *client.Client, container container_types.InspectResponse, command []string) (execCommand, error) {
+			return execCommand{Cmd: command}, nil
+		},
+	},
+	"shell": {
+		detect: func(config *container_types.Config) bool {
+			_, ok := shellFormScript(config, config.Entrypoint)
+			return ok
+		},
+		wrap: func(ctx context.Context, cli *client.Client, container container_types.InspectResponse, command []string) (execCommand, error) {
+			script, ok := shellFormScript(container.Config, container.Config.Entrypoint)
+			if !ok {
+				return execCommand{Cmd: command}, nil
+			}
+			return execCommand{Cmd: append([]string{script}, command...)}, nil
+		},
+	},
+}
+
+// shellBinaries are entrypoints that only start a shell, and
+// therefore do not need to wrap a command check
+var shellBinaries = []string{"ash", "bash", "dash", "sh", "zsh"}
+
+// initBinaries are minimal init processes commonly used as an entrypoint
+var initBinaries = []string{"docker-init", "dumb-init", "tini", "tini-static"}
+
+// ExecWrapperNames returns the sorted names of all exec wrappers
+func ExecWrapperNames() []string {
+	names := []string{}
+	for name := range execWrappers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// DetectExecWrapper returns the name of the exec wrapper used to run a command check in the container
+func (h Healthcheck) DetectExecWrapper(container container_types.InspectResponse) string {
+	if h.ExecWrapper != "" {
+		return h.ExecWrapper
+	}
+
+	if container.Config == nil {
+		return "none"
+	}
+
+	for _, name := range execWrapperOrder {
+		if execWrappers[name].detect(container.Config) {
+			return name
+		}
+	}
+
+	return "none"
+}
+
+// containerShell returns the shell used for shell form instructions,
+// which defaults to /bin/sh -c when the image does not set one
+func containerShell(config *container_types.Config) []string {
+	if len(config.Shell) == 0 {
+		return []string{"/bin/sh", "-c"}
+	}
+
+	return config.Shell
+}
+
+func isCNBContainer(config *container_types.Config) bool {
+	if config.Labels["io.buildpacks.lifecycle.metadata"] != "" {
+		return true
+	}
+
+	if len(config.Entrypoint) == 0 {
+		return false
+	}
+
+	return config.Entrypoint[0] == "/cnb/lifecycle/launcher" || strings.HasPrefix(config.Entrypoint[0], "/cnb/process/")
+}
+
+func isInitBinary(binary string) bool {
+	return slices.Contains(initBinaries, filepath.Base(binary))
+}
+
+func isShellEntrypoint(entrypoint []string) bool {
+	if !slices.Contains(shellBinaries, filepath.Base(entrypoint[0])) {
+		return false
+	}
+
+	for _, arg := range entrypoint[1:] {
+		if !strings.HasPrefix(arg, "-") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// shellFormScript returns the script run by a shell form entrypoint,
+// which is the container shell followed by a single script argument
+func shellFormScript(config *container_types.Config, entrypoint []string) (string, bool) {
+	shell := containerShell(config)
+	if len(entrypoint) != len(shell)+1 || !reflect.DeepEqual(entrypoint[:len(shell)], shell) {
+		return "", false
+	}
+
+	return entrypoint[len(shell)], true
+}
+
+// stripInitEntrypoint removes the init binary and its flags from an
+// entrypoint, returning any remaining entrypoint the init would launch
+func stripInitEntrypoint(config *container_types.Config) []string {
+	args := config.Entrypoint[1:]
+	if index := slices.Index(args, "--"); index >= 0 {
+		args = args[index+1:]
+	} else {
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			args = args[1:]
+		}
+	}
+
+	if len(args) > 0 && isShellEntrypoint(args) {
+		return []string{}
+	}
+
+	// a shell form entrypoint after the init runs the command through
+	// its script, the same as the shell wrapper
+	if script, ok := shellFormScript(config, args); ok {
+		return []string{script}
+	}
+
+	return slices.Clone(args)
+}
+
+// shellQuote quotes an argument for use in a posix shell script
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
+
+// wrapHerokuishCommand runs the command as a shell script via the herokuish
+// /exec helper when it is available, so the buildpack environment is loaded
+// first. The script is copied into a unique directory that is removed once
+// the command completes, and is passed over stdin when no writable directory
+// is available, such as with a read-only root filesystem.
+func wrapHerokuishCommand(ctx context.Context, cli *client.Client, container container_types.InspectResponse, command []string) (execCommand, error) {
+	quoted := []string{}
+	for _, arg := range command {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	script := fmt.Sprintf("#!/bin/sh\n%s\n", strings.Join(quoted, " "))
+
+	prefix := []string{"/exec"}
+	_, err := cli.ContainerStatPath(ctx, container.ID, client.ContainerStatPathOptions{Path: "/exec"})
+	if cerrdefs.IsNotFound(err) {
+		prefix = []string{}
+	} else if err != nil {
+		return execCommand{}, fmt.Errorf("unable to stat path '/exec': %w", err)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return execCommand{}, fmt.Errorf("unable to generate script directory name: %w", err)
+	}
+	name := fmt.Sprintf("healthcheck-%s", hex.EncodeToString(suffix))
+
+	for _, parent := range scriptDirectories(container) {
+		content, err := scriptArchive(name, script)
+		if err != nil {
+			return execCommand{}, err
+		}
+
+		_, err = cli.CopyToContainer(ctx, container.ID, client.CopyToContainerOptions{
+			DestinationPath:           parent,
+			Content:                   content,
+			AllowOverwriteDirWithFile: true,
+		})
+		if err != nil {
+			continue
+		}
+
+		dir := path.Join(parent, name)
+		return execCommand{
+			Cmd:     append(prefix, "/bin/sh", path.Join(dir, "healthcheck.sh")),
+			Cleanup: []string{"rm", "-rf", dir},
+		}, nil
+	}
+
+	return execCommand{
+		Cmd:   append(prefix, "/bin/sh", "-s"),
+		Stdin: []byte(script),
+	}, nil
+}
+
+// scriptDirectories returns the container directories a script may be
+// copied into, which are the tmpfs mounts when the root filesystem is
+// read-only. Other writable mounts are skipped, as they are usually
+// persistent volumes holding user data.
+func scriptDirectories(container container_types.InspectResponse) []string {
+	if container.HostConfig == nil || !container.HostConfig.ReadonlyRootfs {
+		return []string{"/tmp"}
+	}
+
+	directories := []string{}
+	for destination := range container.HostConfig.Tmpfs {
+		directories = append(directories, destination)
+	}
+
+	for _, mountPoint := range container.Mounts {
+		if mountPoint.Type == mount.TypeTmpfs && mountPoint.RW && !slices.Contains(directories, mountPoint.Destination) {
+			directories = append(directories, mountPoint.Destination)
+		}
+	}
+	slices.Sort(directories)
+
+	return directories
+}
+
+// scriptArchive returns a tar archive containing the script in a directory
+func scriptArchive(dir string, script string) (io.Reader, error) {
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	modTime := time.Now()
+
+	if err := writer.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755, ModTime: modTime}); err != nil {
+		return nil, fmt.Errorf("unable to create tar archive: %w", err)
+	}
+
+	if err := writer.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: dir + "/healthcheck.sh", Mode: 0755, Size: int64(len(script)), ModTime: modTime}); err != nil {
+		return nil, fmt.Errorf("unable to create tar archive: %w", err)
+	}
+
+	if _, err := writer.Write([]byte(script)); err != nil {
+		return nil, fmt.Errorf("unable to create tar archive: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("unable to create tar archive: %w", err)
+	}
+
+	return &buffer, nil
+}
@@ -0,0 +1,183 @@
+package appjson
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"slices"
+	"testing"
+
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+)
+
+func TestHealthcheck_DetectExecWrapper(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthcheck Healthcheck
+		config      *container_types.Config
+		want        string
+	}{
+		{
+			name:   "when the container has no entrypoint",
+			config: &container_types.Config{},
+			want:   "none",
+		},
+		{
+			name:   "when the entrypoint is a plain shell",
+			config: &container_types.Config{Entrypoint: []string{"/bin/bash"}},
+			want:   "none",
+		},
+		{
+			name:   "when the entrypoint is in exec form",
+			config: &container_types.Config{Entrypoint: []string{"/docker-entrypoint.sh"}},
+			want:   "entrypoint",
+		},
+		{
+			name:   "when the entrypoint is in shell form",
+			config: &container_types.Config{Entrypoint: []string{"/bin/sh", "-c", "/docker-entrypoint.sh"}},
+			want:   "shell",
+		},
+		{
+			name:   "when the entrypoint is an init process",
+			config: &container_types.Config{Entrypoint: []string{"/usr/bin/tini", "--", "/docker-entrypoint.sh"}},
+			want:   "init",
+		},
+		{
+			name:   "when the image is built with herokuish",
+			config: &container_types.Config{Labels: map[string]string{"com.gliderlabs.herokuish/stack": "heroku-22"}},
+			want:   "herokuish",
+		},
+		{
+			name:   "when the image is built with cloud native buildpacks",
+			config: &container_types.Config{Entrypoint: []string{"/cnb/process/web"}},
+			want:   "cnb",
+		},
+		{
+			name:        "when the exec wrapper is overridden",
+			healthcheck: Healthcheck{ExecWrapper: "none"},
+			config:      &container_types.Config{Entrypoint: []string{"/docker-entrypoint.sh"}},
+			want:        "none",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.healthcheck.DetectExecWrapper(container_types.InspectResponse{Config: tt.config})
+			if got != tt.want {
+				t.Errorf("Healthcheck.DetectExecWrapper() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecWrappers_wrap(t *testing.T) {
+	tests := []struct {
+		name       string
+		wrapper    string
+		entrypoint []string
+		want       []string
+	}{
+		{
+			name:       "when wrapping with the entrypoint",
+			wrapper:    "entrypoint",
+			entrypoint: []string{"/docker-entrypoint.sh"},
+			want:       []string{"/docker-entrypoint.sh", "check"},
+		},
+		{
+			name:       "when wrapping with a shell form entrypoint",
+			wrapper:    "shell",
+			entrypoint: []string{"/bin/sh", "-c", "/docker-entrypoint.sh"},
+			want:       []string{"/docker-entrypoint.sh", "check"},
+		},
+		{
+			name:       "when wrapping with an init process",
+			wrapper:    "init",
+			entrypoint: []string{"/usr/bin/dumb-init", "--single-child", "/docker-entrypoint.sh"},
+			want:       []string{"/docker-entrypoint.sh", "check"},
+		},
+		{
+			name:       "when wrapping with an init process and a shell form entrypoint",
+			wrapper:    "init",
+			entrypoint: []string{"tini", "--", "/bin/sh", "-c", "/entry.sh"},
+			want:       []string{"/entry.sh", "check"},
+		},
+		{
+			name:       "when wrapping with an init process and no entrypoint",
+			wrapper:    "init",
+			entrypoint: []string{"/sbin/tini", "-g", "--"},
+			want:       []string{"check"},
+		},
+		{
+			name:       "when wrapping with the cnb launcher",
+			wrapper:    "cnb",
+			entrypoint: []string{"/cnb/process/web"},
+			want:       []string{"/cnb/lifecycle/launcher", "--", "check"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := container_types.InspectResponse{Config: &container_types.Config{Entrypoint: tt.entrypoint}}
+			cmd, err := execWrappers[tt.wrapper].wrap(context.Background(), nil, container, []string{"check"})
+			if err != nil {
+				t.Fatalf("execWrappers[%s].wrap() error = %v", tt.wrapper, err)
+			}
+
+			if !slices.Equal(cmd.Cmd, tt.want) {
+				t.Errorf("execWrappers[%s].wrap() = %v, want %v", tt.wrapper, cmd.Cmd, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("it's"); got != `'it'"'"'s'` {
+		t.Errorf("shellQuote() = %s", got)
+	}
+}
+
+func TestScriptDirectories(t *testing.T) {
+	container := container_types.InspectResponse{HostConfig: &container_types.HostConfig{}}
+	if got := scriptDirectories(container); !slices.Equal(got, []string{"/tmp"}) {
+		t.Errorf("scriptDirectories() = %v, want [/tmp]", got)
+	}
+
+	container.HostConfig.ReadonlyRootfs = true
+	container.Mounts = []container_types.MountPoint{
+		{Destination: "/config", RW: false},
+		{Destination: "/data", RW: true, Type: mount.TypeVolume},
+	}
+	if got := scriptDirectories(container); len(got) != 0 {
+		t.Errorf("scriptDirectories() = %v, want []", got)
+	}
+
+	container.HostConfig.Tmpfs = map[string]string{"/run": ""}
+	container.Mounts = append(container.Mounts, container_types.MountPoint{Destination: "/scratch", RW: true, Type: mount.TypeTmpfs})
+	if got := scriptDirectories(container); !slices.Equal(got, []string{"/run", "/scratch"}) {
+		t.Errorf("scriptDirectories() = %v, want [/run /scratch]", got)
+	}
+}
+
+func TestScriptArchive(t *testing.T) {
+	content, err := scriptArchive("healthcheck-abc", "#!/bin/sh\ntrue\n")
+	if err != nil {
+		t.Fatalf("scriptArchive() error = %v", err)
+	}
+
+	reader := tar.NewReader(content)
+	names := []string{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("scriptArchive() produced an invalid archive: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+
+	want := []string{"healthcheck-abc/", "healthcheck-abc/healthcheck.sh"}
+	if !slices.Equal(names, want) {
+		t.Errorf("scriptArchive() entries = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,335 @@
+package appjson
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// maxFileContentSize is the largest amount of file content read when checking for expected content
+const maxFileContentSize = 1024 * 1024
+
+type FileAssertion struct {
+	Content          string `json:"content,omitempty"`
+	Exists           *bool  `json:"exists,omitempty"`
+	MinFreeMegabytes int    `json:"minFreeMegabytes,omitempty"`
+	Path             string `json:"path,omitempty"`
+	Writable         *bool  `json:"writable,omitempty"`
+}
+
+func (f FileAssertion) ShouldExist() bool {
+	return f.Exists == nil || *f.Exists
+}
+
+func (f FileAssertion) Validate() error {
+	if f.Path == "" {
+		return errors.New("missing 'path' value")
+	}
+
+	if !path.IsAbs(f.Path) {
+		return fmt.Errorf("'path' value must be absolute: %s", f.Path)
+	}
+
+	if f.MinFreeMegabytes < 0 {
+		return errors.New("'minFreeMegabytes' must not be negative")
+	}
+
+	if !f.ShouldExist() && (f.Content != "" || f.Writable != nil || f.MinFreeMegabytes > 0) {
+		return fmt.Errorf("path '%s' cannot be asserted as missing while also asserting 'content', 'writable', or 'minFreeMegabytes'", f.Path)
+	}
+
+	return nil
+}
+
+type fileChecker struct{}
+
+func (fileChecker) Configured(h Healthcheck) bool {
+	return len(h.Files) > 0
+}
+
+func (fileChecker) Validate(h Healthcheck) error {
+	if len(h.Files) == 0 {
+		return fmt.Errorf("healthcheck name='%s' requires a 'files' value for the 'file' strategy", h.GetName())
+	}
+
+	for _, assertion := range h.Files {
+		if err := assertion.Validate(); err != nil {
+			return fmt.Errorf("healthcheck name='%s' has an invalid 'files' value: %w", h.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (fileChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("attempts=%d files=%d timeout=%d type='file' wait=%d", h.GetAttempts(), len(h.Files), h.GetTimeout(), h.GetWait())
+}
+
+func (fileChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return withDockerClient(hctx, func(cli *client.Client) ([]byte, error) {
+		return h.fileCheck(ctx, cli, container)
+	})
+}
+
+func (h Healthcheck) fileCheck(ctx context.Context, cli *client.Client, container container_types.InspectResponse) ([]byte, error) {
+	lines := []string{}
+	failures := []string{}
+	for _, assertion := range h.Files {
+		line, err := assertFile(ctx, cli, container, assertion)
+		lines = append(lines, line)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	b := []byte(strings.Join(lines, "\n"))
+	if len(failures) > 0 {
+		return b, errors.New(strings.Join(failures, ", "))
+	}
+
+	return b, nil
+}
+
+func assertFile(ctx context.Context, cli *client.Client, container container_types.InspectResponse, assertion FileAssertion) (string, error) {
+	summary := []string{fmt.Sprintf("path='%s'", assertion.Path)}
+
+	stat, err := cli.ContainerStatPath(ctx, container.ID, client.ContainerStatPathOptions{Path: assertion.Path})
+	if err != nil && !cerrdefs.IsNotFound(err) {
+		return strings.Join(summary, " "), fmt.Errorf("unable to stat path '%s': %w", assertion.Path, err)
+	}
+
+	exists := err == nil
+	summary = append(summary, fmt.Sprintf("exists=%t", exists))
+	if !exists {
+		if assertion.ShouldExist() {
+			return strings.Join(summary, " "), fmt.Errorf("path '%s' does not exist", assertion.Path)
+		}
+		return strings.Join(summary, " "), nil
+	}
+
+	if !assertion.ShouldExist() {
+		return strings.Join(summary, " "), fmt.Errorf("path '%s' exists", assertion.Path)
+	}
+
+	summary = append(summary, fmt.Sprintf("mode=%s", stat.Stat.Mode))
+
+	if assertion.Content != "" {
+		content, err := readContainerFile(ctx, cli, container, assertion.Path)
+		if err != nil {
+			return strings.Join(summary, " "), err
+		}
+
+		if !strings.Contains(content, assertion.Content) {
+			return strings.Join(summary, " "), fmt.Errorf("unable to find expected content in path '%s': %s", assertion.Path, assertion.Content)
+		}
+		summary = append(summary, "content=matched")
+	}
+
+	if assertion.Writable == nil && assertion.MinFreeMegabytes == 0 {
+		return strings.Join(summary, " "), nil
+	}
+
+	if container.State == nil || container.State.Pid == 0 {
+		return strings.Join(summary, " "), errors.New("container state is not running")
+	}
+
+	root := fmt.Sprintf("/proc/%d/root", container.State.Pid)
+	hostPath, err := resolveInRoot(root, assertion.Path)
+	if err != nil {
+		return strings.Join(summary, " "), fmt.Errorf("unable to resolve path '%s': %w", assertion.Path, err)
+	}
+
+	if assertion.Writable != nil {
+		user := ""
+		if container.Config != nil {
+			user = container.Config.User
+		}
+
+		uid, gid, err := containerUser(root, user)
+		if err != nil {
+			return strings.Join(summary, " "), err
+		}
+
+		writable, err := pathWritable(hostPath, uid, gid)
+		if err != nil {
+			return strings.Join(summary, " "), fmt.Errorf("unable to check if path '%s' is writable: %w", assertion.Path, err)
+		}
+
+		summary = append(summary, fmt.Sprintf("writable=%t", writable))
+		if writable != *assertion.Writable {
+			if writable {
+				return strings.Join(summary, " "), fmt.Errorf("path '%s' is writable", assertion.Path)
+			}
+			return strings.Join(summary, " "), fmt.Errorf("path '%s' is not writable", assertion.Path)
+		}
+	}
+
+	if assertion.MinFreeMegabytes > 0 {
+		free, err := freeBytes(hostPath)
+		if err != nil {
+			return strings.Join(summary, " "), fmt.Errorf("unable to check free space for path '%s': %w", assertion.Path, err)
+		}
+
+		freeMegabytes := free / 1024 / 1024
+		summary = append(summary, fmt.Sprintf("free_megabytes=%d", freeMegabytes))
+		if freeMegabytes < uint64(assertion.MinFreeMegabytes) {
+			return strings.Join(summary, " "), fmt.Errorf("path '%s' has %dMB free, less than the required %dMB", assertion.Path, freeMegabytes, assertion.MinFreeMegabytes)
+		}
+	}
+
+	return strings.Join(summary, " "), nil
+}
+
+// readContainerFile reads a regular file from the container via the docker archive api
+func readContainerFile(ctx context.Context, cli *client.Client, container container_types.InspectResponse, filePath string) (string, error) {
+	response, err := cli.CopyFromContainer(ctx, container.ID, client.CopyFromContainerOptions{SourcePath: filePath})
+	if err != nil {
+		return "", fmt.Errorf("unable to copy path '%s' from container: %w", filePath, err)
+	}
+	defer response.Content.Close()
+
+	reader := tar.NewReader(response.Content)
+	header, err := reader.Next()
+	if err != nil {
+		return "", fmt.Errorf("unable to read archive for path '%s': %w", filePath, err)
+	}
+
+	if header.Typeflag != tar.TypeReg {
+		return "", fmt.Errorf("path '%s' is not a regular file", filePath)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(reader, maxFileContentSize))
+	if err != nil {
+		return "", fmt.Errorf("unable to read path '%s': %w", filePath, err)
+	}
+
+	return string(b), nil
+}
+
+// resolveInRoot resolves a container path to a host path below root,
+// evaluating any symlinks relative to root rather than the host
+func resolveInRoot(root string, containerPath string) (string, error) {
+	resolved := "/"
+	remaining := strings.Split(strings.TrimPrefix(path.Clean(containerPath), "/"), "/")
+	for hops := 0; len(remaining) > 0; {
+		part := remaining[0]
+		remaining = remaining[1:]
+		if part == "" || part == "." {
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > 255 {
+			return "", errors.New("too many levels of symbolic links")
+		}
+
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			return "", err
+		}
+
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		remaining = append(strings.Split(strings.TrimPrefix(target, "/"), "/"), remaining...)
+	}
+
+	return filepath.Join(root, resolved), nil
+}
+
+// containerUser returns the uid and gid the container user maps to,
+// looking up user and group names in the container's own passwd files
+func containerUser(root string, user string) (int, int, error) {
+	if user == "" {
+		return 0, 0, nil
+	}
+
+	name, group, _ := strings.Cut(user, ":")
+	uid, err := strconv.Atoi(name)
+	gid := 0
+	if err != nil {
+		fields, err := lookupEntry(root, "/etc/passwd", name)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unable to lookup container user '%s': %w", name, err)
+		}
+		if len(fields) < 4 {
+			return 0, 0, fmt.Errorf("unable to lookup container user '%s': invalid passwd entry", name)
+		}
+		if uid, err = strconv.Atoi(fields[2]); err != nil {
+			return 0, 0, fmt.Errorf("unable to lookup container user '%s': %w", name, err)
+		}
+		if gid, err = strconv.Atoi(fields[3]); err != nil {
+			return 0, 0, fmt.Errorf("unable to lookup container user '%s': %w", name, err)
+		}
+	}
+
+	if group == "" {
+		return uid, gid, nil
+	}
+
+	if gid, err = strconv.Atoi(group); err == nil {
+		return uid, gid, nil
+	}
+
+	fields, err := lookupEntry(root, "/etc/group", group)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to lookup container group '%s': %w", group, err)
+	}
+	if len(fields) < 3 {
+		return 0, 0, fmt.Errorf("unable to lookup container group '%s': invalid group entry", group)
+	}
+	if gid, err = strconv.Atoi(fields[2]); err != nil {
+		return 0, 0, fmt.Errorf("unable to lookup container group '%s': %w", group, err)
+	}
+
+	return uid, gid, nil
+}
+
+func lookupEntry(root string, file string, name string) ([]string, error) {
+	hostPath, err := resolveInRoot(root, file)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := os.Open(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	defer handler.Close()
+
+	scanner := bufio.NewScanner(handler)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) > 0 && fields[0] == name {
+			return fields, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("no entry found in %s", file)
+}
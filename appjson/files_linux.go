@@ -0,0 +1,49 @@
+package appjson
+
+import (
+	"syscall"
+)
+
+// stRdonly is the statfs flag set for filesystems mounted read-only
+const stRdonly = 0x1
+
+// pathWritable returns whether the given uid and gid may write to the path
+func pathWritable(hostPath string, uid int, gid int) (bool, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(hostPath, &fs); err != nil {
+		return false, err
+	}
+
+	if fs.Flags&stRdonly != 0 {
+		return false, nil
+	}
+
+	if uid == 0 {
+		return true, nil
+	}
+
+	var stat syscall.Stat_t
+	if err := syscall.Stat(hostPath, &stat); err != nil {
+		return false, err
+	}
+
+	if int(stat.Uid) == uid {
+		return stat.Mode&syscall.S_IWUSR != 0, nil
+	}
+
+	if int(stat.Gid) == gid {
+		return stat.Mode&syscall.S_IWGRP != 0, nil
+	}
+
+	return stat.Mode&syscall.S_IWOTH != 0, nil
+}
+
+// freeBytes returns the number of bytes available to unprivileged users on the path's filesystem
+func freeBytes(hostPath string) (uint64, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(hostPath, &fs); err != nil {
+		return 0, err
+	}
+
+	return fs.Bavail * uint64(fs.Bsize), nil
+}
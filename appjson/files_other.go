@@ -0,0 +1,15 @@
+//go:build !linux
+
+package appjson
+
+import (
+	"errors"
+)
+
+func pathWritable(hostPath string, uid int, gid int) (bool, error) {
+	return false, errors.New("checking if a path is writable is only supported on linux")
+}
+
+func freeBytes(hostPath string) (uint64, error) {
+	return 0, errors.New("checking free disk space is only supported on linux")
+}
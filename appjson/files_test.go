@@ -0,0 +1,58 @@
+package appjson
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveInRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "app", "shared"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/app/shared", filepath.Join(root, "app", "tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("shared", filepath.Join(root, "app", "relative")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		containerPath string
+		want          string
+	}{
+		{
+			name:          "when the path has no symlinks",
+			containerPath: "/app/shared",
+			want:          filepath.Join(root, "app", "shared"),
+		},
+		{
+			name:          "when the path is an absolute symlink",
+			containerPath: "/app/tmp",
+			want:          filepath.Join(root, "app", "shared"),
+		},
+		{
+			name:          "when the path is a relative symlink",
+			containerPath: "/app/relative",
+			want:          filepath.Join(root, "app", "shared"),
+		},
+		{
+			name:          "when the path escapes the root",
+			containerPath: "/../../app/shared",
+			want:          filepath.Join(root, "app", "shared"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveInRoot(root, tt.containerPath)
+			if err != nil {
+				t.Fatalf("resolveInRoot() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveInRoot() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
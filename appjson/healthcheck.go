@@ -3,7 +3,9 @@ package appjson
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,58 +13,122 @@ import (
 	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"os/exec"
-	"reflect"
 	"resty.dev/v3"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alexellis/go-execute/v2"
-	retry "github.com/avast/retry-go"
-	archive "github.com/moby/go-archive"
+	"github.com/moby/moby/api/pkg/stdcopy"
 	container_types "github.com/moby/moby/api/types/container"
 	"github.com/moby/moby/client"
 
 	"docker-container-healthchecker/logger"
 )
 
-type CheckType int
+// CheckType is the name of a check strategy
+type CheckType string
 
 const (
-	CommandCheck CheckType = iota
-	ListeningCheck
-	PathCheck
-	UptimeCheck
+	CommandCheck      CheckType = "command"
+	ListeningCheck    CheckType = "listening"
+	PathCheck         CheckType = "path"
+	UptimeCheck       CheckType = "uptime"
+	DockerHealthCheck CheckType = "dockerHealth"
+	LogsCheck         CheckType = "logs"
+	ResourcesCheck    CheckType = "resources"
+	ProcessCheck      CheckType = "process"
+	ZombiesCheck      CheckType = "zombies"
+	FileCheck         CheckType = "file"
+	EnvCheck          CheckType = "env"
+	HostCommandCheck  CheckType = "hostCommand"
 )
 
+// maxCommandOutputSize is the largest amount of stdout or stderr kept from a command check
+const maxCommandOutputSize = 64 * 1024
+
 var validAddresses = map[string]bool{
 	"0.0.0.0": true,
 	"::":      true,
 }
 
 type AppJSON struct {
-	Healthchecks map[string][]Healthcheck `json:"healthchecks"`
+	Healthchecks        map[string][]Healthcheck       `json:"healthchecks"`
+	HealthcheckSettings map[string]HealthcheckSettings `json:"healthcheckSettings,omitempty"`
+}
+
+// HealthcheckSettings holds the options that apply to every
+// healthcheck of a process type when it is checked
+type HealthcheckSettings struct {
+	// Concurrency is the maximum number of checks run at once, with 0 meaning no limit
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// Deadline is the number of seconds after which any remaining checks are cancelled
+	Deadline int `json:"deadline,omitempty"`
+}
+
+// GetSettings returns the healthcheck settings for a process type
+func (a AppJSON) GetSettings(processType string) HealthcheckSettings {
+	return a.HealthcheckSettings[processType]
+}
+
+func (s HealthcheckSettings) Validate() error {
+	if s.Concurrency < 0 {
+		return errors.New("cannot contain a negative 'concurrency' value")
+	}
+
+	if s.Deadline < 0 {
+		return errors.New("cannot contain a negative 'deadline' value")
+	}
+
+	return nil
 }
 
 type Healthcheck struct {
-	Attempts     int          `json:"attempts,omitempty"`
-	Command      []string     `json:"command,omitempty"`
-	Content      string       `json:"content,omitempty"`
-	HTTPHeaders  []HTTPHeader `json:"httpHeaders,omitempty"`
-	InitialDelay int          `json:"initialDelay,omitempty"`
-	Listening    bool         `json:"listening,omitempty"`
-	Name         string       `json:"name,omitempty"`
-	Path         string       `json:"path,omitempty"`
-	Port         int          `json:"port,omitempty"`
-	Scheme       string       `json:"scheme,omitempty"`
-	Timeout      int          `json:"timeout,omitempty"`
-	Type         string       `json:"type,omitempty"`
-	Uptime       int          `json:"uptime,omitempty"`
-	Wait         int          `json:"wait,omitempty"`
-	Warn         bool         `json:"warn,omitempty"`
-	OnFailure    *OnFailure   `json:"onFailure,omitempty"`
+	Attempts         int              `json:"attempts,omitempty"`
+	Backoff          string           `json:"backoff,omitempty"`
+	Command          []string         `json:"command,omitempty"`
+	Content          string           `json:"content,omitempty"`
+	DependsOn        []string         `json:"dependsOn,omitempty"`
+	DockerHealth     bool             `json:"dockerHealth,omitempty"`
+	Env              []string         `json:"env,omitempty"`
+	ExecWrapper      string           `json:"execWrapper,omitempty"`
+	EnvAssertions    []EnvAssertion   `json:"envAssertions,omitempty"`
+	FailureThreshold int              `json:"failureThreshold,omitempty"`
+	Files            []FileAssertion  `json:"files,omitempty"`
+	Format           string           `json:"format,omitempty"`
+	HostCommand      []string         `json:"hostCommand,omitempty"`
+	HTTPHeaders      []HTTPHeader     `json:"httpHeaders,omitempty"`
+	InitialDelay     int              `json:"initialDelay,omitempty"`
+	Jitter           int              `json:"jitter,omitempty"`
+	Listening        bool             `json:"listening,omitempty"`
+	LogLines         int              `json:"logLines,omitempty"`
+	Logs             *LogPatterns     `json:"logs,omitempty"`
+	MaxRestarts      int              `json:"maxRestarts,omitempty"`
+	MaxWait          int              `json:"maxWait,omitempty"`
+	Name             string           `json:"name,omitempty"`
+	Options          json.RawMessage  `json:"options,omitempty"`
+	Path             string           `json:"path,omitempty"`
+	Port             int              `json:"port,omitempty"`
+	Privileged       bool             `json:"privileged,omitempty"`
+	Processes        []ProcessMatcher `json:"processes,omitempty"`
+	Resources        *ResourceLimits  `json:"resources,omitempty"`
+	Scheme           string           `json:"scheme,omitempty"`
+	Strategy         string           `json:"strategy,omitempty"`
+	SuccessExitCodes []int            `json:"successExitCodes,omitempty"`
+	SuccessThreshold int              `json:"successThreshold,omitempty"`
+	Timeout          int              `json:"timeout,omitempty"`
+	Type             string           `json:"type,omitempty"`
+	Uptime           int              `json:"uptime,omitempty"`
+	User             string           `json:"user,omitempty"`
+	Wait             int              `json:"wait,omitempty"`
+	Warn             bool             `json:"warn,omitempty"`
+	WarnExitCodes    []int            `json:"warnExitCodes,omitempty"`
+	WorkingDir       string           `json:"workingDir,omitempty"`
+	Zombies          *ZombieThreshold `json:"zombies,omitempty"`
+	OnFailure        *OnFailure       `json:"onFailure,omitempty"`
 }
 
 type HTTPHeader struct {
@@ -75,33 +141,80 @@ type OnFailure struct {
 	Url     string   `json:"url,omitempty"`
 }
 
+// ErrCancelled is returned when a healthcheck is interrupted before it completes
+var ErrCancelled = errors.New("cancelled")
+
+// ErrTimedOut is returned when a healthcheck is still running once the check deadline passes
+var ErrTimedOut = errors.New("timed out")
+
+// ContextError maps the reason a context ended to the error reported for an unfinished healthcheck
+func ContextError(ctx context.Context) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ErrTimedOut
+	case errors.Is(ctx.Err(), context.Canceled):
+		return ErrCancelled
+	}
+
+	return nil
+}
+
+// WarningError is returned by a check that completed with a non-fatal result
+type WarningError struct {
+	Err error
+}
+
+func (e WarningError) Error() string {
+	return e.Err.Error()
+}
+
+func (e WarningError) Unwrap() error {
+	return e.Err
+}
+
+// IsWarning returns whether the error is a non-fatal check result
+func IsWarning(err error) bool {
+	var warning WarningError
+	return errors.As(err, &warning)
+}
+
 type HealthcheckContext struct {
-	Headers   []string
-	IPAddress string
-	Network   string
-	Port      int
+	Headers     []string
+	IPAddress   string
+	Network     string
+	Port        int
+	ProcessType string
+
+	// OnAttempt is called with the record of every attempt once it completes
+	OnAttempt func(Attempt)
+
+	// Client is the docker client shared by every attempt. When unset,
+	// each attempt creates its own client and closes it once done.
+	Client *client.Client
 }
 
+// GetAttempts returns the maximum number of attempts, which defaults to
+// enough attempts to reach the success threshold after all but the last
+// of the failures allowed by the failure threshold
 func (h Healthcheck) GetAttempts() int {
-	defaultAttempts := 3
 	if h.Attempts <= 0 {
-		return defaultAttempts
+		return h.GetFailureThreshold() + h.GetSuccessThreshold() - 1
 	}
 
 	return h.Attempts
 }
 
+// GetCheckType returns the check strategy selected by the healthcheck,
+// defaulting to an uptime check when no strategy is configured
 func (h Healthcheck) GetCheckType() CheckType {
-	if h.Listening {
-		return ListeningCheck
+	if h.Strategy != "" {
+		return CheckType(h.Strategy)
 	}
 
-	if len(h.Command) > 0 {
-		return CommandCheck
-	}
-
-	if h.Path != "" {
-		return PathCheck
+	for _, name := range CheckerNames() {
+		if checker, ok := GetChecker(name); ok && checker.Configured(h) {
+			return name
+		}
 	}
 
 	return UptimeCheck
@@ -115,6 +228,22 @@ func (h Healthcheck) GetInitialDelay() int {
 	return h.InitialDelay
 }
 
+// GetMaxDuration returns the worst-case time the healthcheck can take,
+// assuming the full initial delay and every attempt timing out
+func (h Healthcheck) GetMaxDuration() time.Duration {
+	delay := time.Duration(h.GetInitialDelay()) * time.Second
+	checker, ok := GetChecker(h.GetCheckType())
+	if !ok {
+		return delay
+	}
+
+	if delayer, ok := checker.(startDelayer); ok {
+		delay += delayer.startDelay(h, 0)
+	}
+
+	return delay + time.Duration(h.GetAttempts())*h.attemptTimeout(checker) + h.retryWaitDuration()
+}
+
 func (h Healthcheck) GetName() string {
 	if h.Name != "" {
 		return h.Name
@@ -141,6 +270,15 @@ func (h Healthcheck) GetRetries() int {
 	return attempts - 1
 }
 
+// GetSuccessExitCodes returns the command exit codes treated as a passing check
+func (h Healthcheck) GetSuccessExitCodes() []int {
+	if len(h.SuccessExitCodes) == 0 {
+		return []int{0}
+	}
+
+	return h.SuccessExitCodes
+}
+
 func (h Healthcheck) GetTimeout() int {
 	if h.Timeout <= 0 {
 		return 5
@@ -158,57 +296,113 @@ func (h Healthcheck) GetWait() int {
 }
 
 func (h Healthcheck) Validate() error {
-	if len(h.Command) > 0 {
-		if h.Path != "" {
-			return fmt.Errorf("healthcheck name='%s' cannot contain both a container 'command' to execute and an http 'path' to check", h.GetName())
-		} else if h.Uptime > 0 {
-			return fmt.Errorf("healthcheck name='%s' cannot contain both a container 'command' to execute and an 'uptime' seconds value", h.GetName())
-		} else if h.Listening {
-			return fmt.Errorf("healthcheck name='%s' cannot contain both a container 'command' to execute and a 'listening' true value", h.GetName())
-		}
+	if len(h.Options) > 0 && h.Strategy == "" {
+		return fmt.Errorf("healthcheck name='%s' can only contain an 'options' value alongside a 'strategy' value", h.GetName())
+	}
+
+	if h.MaxRestarts < 0 {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a negative 'maxRestarts' value", h.GetName())
+	}
+
+	if h.LogLines < 0 {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a negative 'logLines' value", h.GetName())
 	}
 
-	if h.Path != "" {
-		if h.Uptime > 0 {
-			return fmt.Errorf("healthcheck name='%s' cannot contain both an http 'path' to check and an 'uptime' seconds value", h.GetName())
-		} else if h.Listening {
-			return fmt.Errorf("healthcheck name='%s' cannot contain both an http 'path' to check and a 'listening' true value", h.GetName())
+	if err := h.validateBackoff(); err != nil {
+		return err
+	}
+
+	if err := h.validateThresholds(); err != nil {
+		return err
+	}
+
+	if slices.Contains(h.DependsOn, "") {
+		return fmt.Errorf("healthcheck name='%s' cannot contain an empty 'dependsOn' value", h.GetName())
+	}
+
+	if strategies := h.configuredStrategies(); len(strategies) > 1 {
+		return fmt.Errorf("healthcheck name='%s' cannot contain more than one check strategy: %s", h.GetName(), strings.Join(strategies, ", "))
+	}
+
+	checkType := h.GetCheckType()
+	for _, name := range CheckerNames() {
+		if name == checkType {
+			continue
+		}
+
+		checker, _ := GetChecker(name)
+		if validator, ok := checker.(unselectedValidator); ok {
+			if err := validator.validateUnselected(h); err != nil {
+				return err
+			}
 		}
 	}
 
-	if h.Uptime > 0 && h.Listening {
-		return fmt.Errorf("healthcheck name='%s' cannot contain both an 'uptime' seconds value and a 'listening' true value", h.GetName())
+	checker, ok := GetChecker(checkType)
+	if !ok {
+		return fmt.Errorf("healthcheck name='%s' has an invalid 'strategy' value: unknown check strategy '%s'", h.GetName(), h.Strategy)
 	}
 
-	return nil
+	return checker.Validate(h)
 }
 
-func (h Healthcheck) Execute(container container_types.InspectResponse, ctx HealthcheckContext) ([]byte, []error) {
-	if err := h.Validate(); err != nil {
-		return []byte{}, []error{err}
+// configuredStrategies returns the sorted name of every check strategy with a value set
+func (h Healthcheck) configuredStrategies() []string {
+	strategies := []string{}
+	for _, name := range CheckerNames() {
+		if checker, ok := GetChecker(name); ok && checker.Configured(h) {
+			strategies = append(strategies, string(name))
+		}
+	}
+
+	if h.Strategy != "" && !slices.Contains(strategies, h.Strategy) {
+		strategies = append(strategies, h.Strategy)
 	}
+	slices.Sort(strategies)
+
+	return strategies
+}
 
-	if len(h.Command) > 0 {
-		return h.executeCommandCheck(container)
+// Describe returns the key=value pairs logged when the healthcheck runs
+func (h Healthcheck) Describe(container container_types.InspectResponse) string {
+	checker, ok := GetChecker(h.GetCheckType())
+	if !ok {
+		return fmt.Sprintf("name='%s' type='%s'", h.GetName(), h.GetCheckType())
 	}
 
-	if h.Path != "" {
-		return h.executePathCheck(container, ctx)
+	return fmt.Sprintf("name='%s' %s", h.GetName(), checker.Describe(h, container))
+}
+
+// Execute runs the attempts of the healthcheck, stopping early when the context is cancelled
+func (h Healthcheck) Execute(ctx context.Context, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, []error) {
+	if err := h.Validate(); err != nil {
+		return []byte{}, []error{err}
 	}
 
-	if h.Listening {
-		return h.executeListenerCheck(container)
+	checker, _ := GetChecker(h.GetCheckType())
+	b, errs := h.runAttempts(ctx, checker, container, hctx)
+	if err := ContextError(ctx); err != nil {
+		return b, append(errs, err)
 	}
 
-	return h.executeUptimeCheck(container)
+	return b, errs
 }
 
-func (h Healthcheck) HandleFailure(errors []error) error {
+// onFailureTimeout limits how long the onFailure hooks of a healthcheck may run for
+const onFailureTimeout = 30 * time.Second
+
+// HandleFailure runs the onFailure hooks of the healthcheck, stopping
+// them once the context is done or the hook timeout passes
+func (h Healthcheck) HandleFailure(ctx context.Context, errors []error) error {
 	if h.OnFailure == nil {
 		return nil
 	}
+
+	ctx, cancel := context.WithTimeout(ctx, onFailureTimeout)
+	defer cancel()
+
 	if len(h.OnFailure.Command) > 0 {
-		cmd := exec.Command(h.OnFailure.Command[0], h.OnFailure.Command[1:]...)
+		cmd := exec.CommandContext(ctx, h.OnFailure.Command[0], h.OnFailure.Command[1:]...)
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("failed to execute on failure command: %s", err)
 		}
@@ -222,7 +416,14 @@ func (h Healthcheck) HandleFailure(errors []error) error {
 		if err != nil {
 			return fmt.Errorf("failed to encode data as JSON: %s", err)
 		}
-		response, err := http.Post(h.OnFailure.Url, "application/json", bytes.NewBuffer(json_data))
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, h.OnFailure.Url, bytes.NewBuffer(json_data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %s", err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+
+		client := &http.Client{Timeout: onFailureTimeout}
+		response, err := client.Do(request)
 		if err != nil {
 			return fmt.Errorf("failed to send data to URL: %s", err)
 		}
@@ -234,111 +435,198 @@ func (h Healthcheck) HandleFailure(errors []error) error {
 	return nil
 }
 
-func (h Healthcheck) executeCommandCheck(container container_types.InspectResponse) ([]byte, []error) {
-	var b []byte
-	err := retry.Do(
-		func() error {
-			var rerr error
-			b, rerr = h.dockerExec(container)
-			return rerr
-		},
-		retry.Attempts(uint(h.GetAttempts())),
-		retry.Delay(time.Duration(h.GetWait())*time.Second),
-	)
-
-	if err != nil {
-		return b, err.(retry.Error).WrappedErrors()
-	}
+type commandChecker struct{}
 
-	return b, nil
+func (commandChecker) Configured(h Healthcheck) bool {
+	return len(h.Command) > 0
 }
 
-func (h Healthcheck) dockerExec(container container_types.InspectResponse) ([]byte, error) {
-	ctx := context.Background()
-	if h.GetTimeout() > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(h.GetTimeout())*time.Second)
-		defer cancel()
+func (commandChecker) Validate(h Healthcheck) error {
+	if len(h.Command) == 0 {
+		return fmt.Errorf("healthcheck name='%s' requires a 'command' value for the 'command' strategy", h.GetName())
 	}
 
-	cli, err := client.NewClientWithOpts(
-		client.FromEnv,
-		client.WithAPIVersionNegotiation(),
-	)
-	if err != nil {
-		return nil, err
+	if h.ExecWrapper != "" {
+		if _, ok := execWrappers[h.ExecWrapper]; !ok {
+			return fmt.Errorf("healthcheck name='%s' has an invalid 'execWrapper' value: must be one of %s", h.GetName(), strings.Join(ExecWrapperNames(), ", "))
+		}
 	}
 
-	shell := container.Config.Shell
-	entrypoint := container.Config.Entrypoint
-	if len(entrypoint) == len(shell)+1 && reflect.DeepEqual(entrypoint[:len(shell)], shell) {
-		h.Command = append([]string{entrypoint[2]}, h.Command...)
-	} else if len(entrypoint) > 0 && !reflect.DeepEqual(entrypoint, shell) {
-		h.Command = append(entrypoint, h.Command...)
-	} else if container.Config.Labels["com.gliderlabs.herokuish/stack"] != "" {
-		handler, err := os.CreateTemp("/tmp", "healthcheck-*")
-		if err != nil {
-			return nil, fmt.Errorf("unable to create temporary file: %w", err)
+	if h.Format != "" {
+		if h.Format != "nagios" {
+			return fmt.Errorf("healthcheck name='%s' has an invalid 'format' value: must be nagios", h.GetName())
+		} else if len(h.SuccessExitCodes) > 0 || len(h.WarnExitCodes) > 0 {
+			return fmt.Errorf("healthcheck name='%s' cannot contain both a 'nagios' format and 'successExitCodes' or 'warnExitCodes' values", h.GetName())
 		}
-		defer os.Remove(handler.Name())
+	}
 
-		command := []string{}
-		for _, arg := range h.Command {
-			command = append(command, strconv.Quote(arg))
+	successExitCodes := map[int]bool{}
+	for _, code := range h.SuccessExitCodes {
+		if code < 0 || code > 255 {
+			return fmt.Errorf("healthcheck name='%s' has an invalid 'successExitCodes' value: exit code %d must be between 0 and 255", h.GetName(), code)
 		}
+		successExitCodes[code] = true
+	}
 
-		script := fmt.Sprintf("#!/bin/bash\n%s\n", strings.Join(command, " "))
-		if _, err := handler.WriteString(script); err != nil {
-			return nil, fmt.Errorf("unable to write to temporary file: %w", err)
+	for _, code := range h.WarnExitCodes {
+		if code < 0 || code > 255 {
+			return fmt.Errorf("healthcheck name='%s' has an invalid 'warnExitCodes' value: exit code %d must be between 0 and 255", h.GetName(), code)
 		}
-
-		if err := handler.Chmod(os.FileMode(0755)); err != nil {
-			return nil, fmt.Errorf("unable to change file mode: %w", err)
+		if successExitCodes[code] || (len(h.SuccessExitCodes) == 0 && code == 0) {
+			return fmt.Errorf("healthcheck name='%s' cannot contain exit code %d in both 'successExitCodes' and 'warnExitCodes'", h.GetName(), code)
 		}
+	}
 
-		if err := handler.Close(); err != nil {
-			return nil, fmt.Errorf("unable to close file: %w", err)
-		}
+	if h.WorkingDir != "" && !strings.HasPrefix(h.WorkingDir, "/") {
+		return fmt.Errorf("healthcheck name='%s' must contain an absolute 'workingDir' value", h.GetName())
+	}
 
-		srcInfo, err := archive.CopyInfoSourcePath(handler.Name(), false)
-		if err != nil {
-			return nil, fmt.Errorf("unable to prepare source copy info: %w", err)
+	for _, variable := range h.Env {
+		name, _, ok := strings.Cut(variable, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("healthcheck name='%s' must contain 'env' values in KEY=value format", h.GetName())
 		}
+	}
 
-		srcArchive, err := archive.TarResource(srcInfo)
-		if err != nil {
-			return nil, fmt.Errorf("unable to create tar archive: %w", err)
-		}
-		defer srcArchive.Close()
+	return nil
+}
 
-		dstInfo := archive.CopyInfo{Path: handler.Name()}
-		dstDir, preparedArchive, err := archive.PrepareArchiveCopy(srcArchive, srcInfo, dstInfo)
-		if err != nil {
-			return nil, fmt.Errorf("unable to prepare archive copy: %w", err)
-		}
-		defer preparedArchive.Close()
+// validateUnselected rejects the exec options of a container command on a healthcheck without one
+func (commandChecker) validateUnselected(h Healthcheck) error {
+	if h.User != "" || h.WorkingDir != "" || len(h.Env) > 0 || h.Privileged {
+		return fmt.Errorf("healthcheck name='%s' can only contain 'user', 'workingDir', 'env', or 'privileged' values alongside a container 'command' to execute", h.GetName())
+	} else if h.ExecWrapper != "" {
+		return fmt.Errorf("healthcheck name='%s' can only contain an 'execWrapper' value alongside a container 'command' to execute", h.GetName())
+	} else if len(h.SuccessExitCodes) > 0 || len(h.WarnExitCodes) > 0 {
+		return fmt.Errorf("healthcheck name='%s' can only contain 'successExitCodes' or 'warnExitCodes' values alongside a container 'command' to execute", h.GetName())
+	} else if h.Format != "" {
+		return fmt.Errorf("healthcheck name='%s' can only contain a 'format' value alongside a container 'command' to execute", h.GetName())
+	}
 
-		_, err = cli.CopyToContainer(ctx, container.ID, client.CopyToContainerOptions{
-			DestinationPath:           dstDir,
-			Content:                   preparedArchive,
-			AllowOverwriteDirWithFile: true,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("unable to copy file to container: %w", err)
-		}
+	return nil
+}
 
-		h.Command = []string{"/exec", "bash", handler.Name()}
+func (commandChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	line := fmt.Sprintf("attempts=%d command='%s' timeout=%d type='command' wait=%d execWrapper='%s'", h.GetAttempts(), h.Command, h.GetTimeout(), h.GetWait(), h.DetectExecWrapper(container))
+	if h.Format != "" {
+		line += fmt.Sprintf(" format='%s'", h.Format)
+	}
+	if len(h.SuccessExitCodes) > 0 || len(h.WarnExitCodes) > 0 {
+		line += fmt.Sprintf(" successExitCodes=%v warnExitCodes=%v", h.GetSuccessExitCodes(), h.WarnExitCodes)
 	}
 
-	return runCommandInContainer(ctx, cli, container, h.Command)
+	return line
 }
 
-func runCommandInContainer(ctx context.Context, cli *client.Client, container container_types.InspectResponse, command []string) ([]byte, error) {
-	response, err := cli.ExecCreate(ctx, container.ID, client.ExecCreateOptions{
-		Cmd:          command,
-		AttachStdout: true,
-		AttachStderr: true,
+func (commandChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return withDockerClient(hctx, func(cli *client.Client) ([]byte, error) {
+		return h.mapExitCode(h.dockerExec(ctx, cli, container))
 	})
+}
+
+// mapExitCode maps the exit code of a command check onto a passing,
+// warning or failing result using the configured exit code lists
+func (h Healthcheck) mapExitCode(b []byte, err error) ([]byte, error) {
+	if h.Format == "nagios" {
+		return mapNagiosResult(b, err)
+	}
+
+	if len(h.SuccessExitCodes) == 0 && len(h.WarnExitCodes) == 0 {
+		return b, err
+	}
+
+	exitCode := 0
+	var exitErr exitCodeError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode
+	} else if err != nil {
+		return b, err
+	}
+
+	if slices.Contains(h.GetSuccessExitCodes(), exitCode) {
+		if exitCode != 0 {
+			if len(b) > 0 && !bytes.HasSuffix(b, []byte("\n")) {
+				b = append(b, '\n')
+			}
+			b = append(b, fmt.Sprintf("exit code %d mapped to success", exitCode)...)
+		}
+		return b, nil
+	}
+
+	if slices.Contains(h.WarnExitCodes, exitCode) {
+		return b, WarningError{Err: fmt.Errorf("exit code %d mapped to warning", exitCode)}
+	}
+
+	if exitCode == 0 {
+		return b, errors.New("exit code 0 mapped to failure")
+	}
+
+	return b, fmt.Errorf("non-zero exit code %d mapped to failure", exitCode)
+}
+
+// SleepContext waits for the duration, returning the context
+// error early when the context is done first
+func SleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h Healthcheck) dockerExec(ctx context.Context, cli *client.Client, container container_types.InspectResponse) ([]byte, error) {
+	name := h.DetectExecWrapper(container)
+	wrapper, ok := execWrappers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown exec wrapper '%s'", name)
+	}
+
+	cmd, err := wrapper.wrap(ctx, cli, container, h.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cmd.Cleanup) > 0 {
+		defer func() {
+			// the check context may already have expired, and a failed
+			// cleanup does not change the result of the check
+			cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cleanupCancel()
+			_, _ = runCommandInContainer(cleanupCtx, cli, container, client.ExecCreateOptions{Cmd: cmd.Cleanup, User: "0"}, nil)
+		}()
+	}
+
+	return runCommandInContainer(ctx, cli, container, client.ExecCreateOptions{
+		Cmd:        cmd.Cmd,
+		Env:        h.Env,
+		Privileged: h.Privileged,
+		User:       h.User,
+		WorkingDir: h.WorkingDir,
+	}, cmd.Stdin)
+}
+
+func runCommandInContainer(ctx context.Context, cli *client.Client, container container_types.InspectResponse, options client.ExecCreateOptions, stdin []byte) ([]byte, error) {
+	marker := make([]byte, 8)
+	if _, err := rand.Read(marker); err != nil {
+		return nil, fmt.Errorf("unable to generate exec id: %w", err)
+	}
+	execID := hex.EncodeToString(marker)
+
+	// the exec id marks the environment of the command and every process
+	// it starts, so they can be found and killed if the check is cancelled
+	options.Env = append(slices.Clone(options.Env), fmt.Sprintf("%s=%s", execIDEnv, execID))
+	options.AttachStdin = stdin != nil
+	options.AttachStdout = true
+	options.AttachStderr = true
+	response, err := cli.ExecCreate(ctx, container.ID, options)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create exec: %w", err)
 	}
@@ -349,6 +637,34 @@ func runCommandInContainer(ctx context.Context, cli *client.Client, container co
 	}
 	defer hijack.Close()
 
+	if stdin != nil {
+		go func() {
+			// closing the write side signals the end of input to the command
+			_, _ = hijack.Conn.Write(stdin)
+			_ = hijack.CloseWrite()
+		}()
+	}
+
+	// the stream must be drained while the command runs, otherwise
+	// commands with large amounts of output block on a full pipe
+	stdout := &cappedBuffer{limit: maxCommandOutputSize}
+	stderr := &cappedBuffer{limit: maxCommandOutputSize}
+	copyErr := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, hijack.Reader)
+		copyErr <- err
+	}()
+
+	select {
+	case err := <-copyErr:
+		if err != nil {
+			return nil, fmt.Errorf("unable to read exec output: %w", err)
+		}
+	case <-ctx.Done():
+		killExec(cli, container.ID, execID)
+		return commandOutput(stdout, stderr), fmt.Errorf("unable to read exec output: %w", ctx.Err())
+	}
+
 	var exitCode int
 	for {
 		execResp, err := cli.ExecInspect(ctx, response.ID, client.ExecInspectOptions{})
@@ -364,46 +680,159 @@ func runCommandInContainer(ctx context.Context, cli *client.Client, container co
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	b, _ := io.ReadAll(hijack.Reader)
+	b := commandOutput(stdout, stderr)
 	if exitCode != 0 {
-		return b, fmt.Errorf("non-zero exit code %d", exitCode)
+		return b, exitCodeError{ExitCode: exitCode}
 	}
-	return nil, nil
+	return b, nil
 }
 
-func (h Healthcheck) executePathCheck(container container_types.InspectResponse, ctx HealthcheckContext) ([]byte, []error) {
-	ipAddress := ctx.IPAddress
-	if ipAddress == "" {
-		endpoint, ok := container.NetworkSettings.Networks[ctx.Network]
-		if !ok {
-			return []byte{}, []error{fmt.Errorf("inspect container: container '%s' not connected to network '%s'", container.ID, ctx.Network)}
+// execIDEnv is the environment variable marking the processes of an exec
+const execIDEnv = "HEALTHCHECK_EXEC_ID"
+
+// killExec kills the processes of an exec that is still running once its
+// context is done, as the docker api has no way to stop an exec. A second
+// exec run as root finds the processes by the exec id in their environment,
+// under its own bounded context as the check context has already expired.
+func killExec(cli *client.Client, containerID string, execID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	script := fmt.Sprintf(`for p in /proc/[0-9]*; do tr '\0' '\n' 2>/dev/null < "$p/environ" | grep -qxF '%s=%s' && kill -9 "${p#/proc/}"; done`, execIDEnv, execID)
+	response, err := cli.ExecCreate(ctx, containerID, client.ExecCreateOptions{
+		Cmd:  []string{"/bin/sh", "-c", script},
+		User: "0",
+	})
+	if err != nil {
+		return
+	}
+
+	if _, err := cli.ExecStart(ctx, response.ID, client.ExecStartOptions{Detach: true}); err != nil {
+		return
+	}
+
+	// wait for the kill to complete so the command is gone before any cleanup runs
+	for ctx.Err() == nil {
+		inspect, err := cli.ExecInspect(ctx, response.ID, client.ExecInspectOptions{})
+		if err != nil || !inspect.Running {
+			return
 		}
 
-		if endpoint.IPAddress.IsValid() {
-			ipAddress = endpoint.IPAddress.String()
+		if SleepContext(ctx, 100*time.Millisecond) != nil {
+			return
 		}
 	}
+}
+
+// exitCodeError is returned when a command exits with a non-zero exit code
+type exitCodeError struct {
+	ExitCode int
+}
+
+func (e exitCodeError) Error() string {
+	return fmt.Sprintf("non-zero exit code %d", e.ExitCode)
+}
+
+// commandOutput joins the demultiplexed stdout and stderr of a command
+func commandOutput(stdout *cappedBuffer, stderr *cappedBuffer) []byte {
+	b := stdout.Bytes()
+	if stderr.Len() > 0 {
+		if len(b) > 0 && !bytes.HasSuffix(b, []byte("\n")) {
+			b = append(b, '\n')
+		}
+		b = append(b, stderr.Bytes()...)
+	}
+
+	return b
+}
+
+// cappedBuffer is an io.Writer that keeps at most limit bytes, silently
+// discarding the rest so the writer is never blocked
+type cappedBuffer struct {
+	buffer    bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	remaining := c.limit - c.buffer.Len()
+	if remaining <= 0 {
+		c.truncated = c.truncated || len(p) > 0
+		return len(p), nil
+	}
+
+	if len(p) > remaining {
+		c.buffer.Write(p[:remaining])
+		c.truncated = true
+		return len(p), nil
+	}
+
+	c.buffer.Write(p)
+	return len(p), nil
+}
+
+func (c *cappedBuffer) Len() int {
+	return c.buffer.Len()
+}
+
+func (c *cappedBuffer) Bytes() []byte {
+	b := append([]byte{}, c.buffer.Bytes()...)
+	if c.truncated {
+		b = append(b, []byte(fmt.Sprintf("\n[output truncated to %d bytes]", c.limit))...)
+	}
+
+	return b
+}
+
+// containerIPAddress returns the ip address override from the context,
+// falling back to the container address on the configured network
+func containerIPAddress(container container_types.InspectResponse, ctx HealthcheckContext) (string, error) {
+	if ctx.IPAddress != "" {
+		return ctx.IPAddress, nil
+	}
+
+	endpoint, ok := container.NetworkSettings.Networks[ctx.Network]
+	if !ok {
+		return "", fmt.Errorf("inspect container: container '%s' not connected to network '%s'", container.ID, ctx.Network)
+	}
+
+	if endpoint.IPAddress.IsValid() {
+		return endpoint.IPAddress.String(), nil
+	}
+
+	return "", nil
+}
+
+type pathChecker struct{}
+
+func (pathChecker) Configured(h Healthcheck) bool {
+	return h.Path != ""
+}
+
+func (pathChecker) Validate(h Healthcheck) error {
+	return nil
+}
+
+func (pathChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("delay=%d path='%s' retries=%d timeout=%d type='path'", h.GetInitialDelay(), h.GetPath(), h.GetRetries(), h.GetTimeout())
+}
+
+func (pathChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	ipAddress, err := containerIPAddress(container, hctx)
+	if err != nil {
+		return []byte{}, Unrecoverable(err)
+	}
 
 	client := resty.New()
 	defer client.Close()
 
 	client.RemoveProxy()
 	client.SetLogger(logger.CreateLogger())
-	client.SetRetryCount(h.GetRetries())
-	client.SetRetryWaitTime(time.Duration(h.GetWait()) * time.Second)
-	client.SetRetryDefaultConditions(false)
-	client.AddRetryConditions(func(response *resty.Response, err error) bool {
-		return err != nil || !response.IsStatusSuccess()
-	})
 
-	if h.GetTimeout() > 0 {
-		client.SetTimeout(time.Duration(h.GetTimeout()) * time.Second)
-	}
-
-	for _, header := range ctx.Headers {
+	for _, header := range hctx.Headers {
 		parts := strings.SplitN(header, ":", 2)
 		if len(parts) != 2 {
-			return []byte{}, []error{fmt.Errorf("invalid header, must be delimited by ':' (colon) character: '%s'", header)}
+			return []byte{}, Unrecoverable(fmt.Errorf("invalid header, must be delimited by ':' (colon) character: '%s'", header))
 		}
 
 		client.SetHeader(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
@@ -425,91 +854,139 @@ func (h Healthcheck) executePathCheck(container container_types.InspectResponse,
 		"https": true,
 	}
 	if !validSchemes[scheme] {
-		return []byte{}, []error{errors.New("invalid scheme specified, must be either http or https")}
+		return []byte{}, Unrecoverable(errors.New("invalid scheme specified, must be either http or https"))
 	}
 
-	request := client.R()
-	resp, err := request.
-		Get(fmt.Sprintf("%s://%s:%d%s", scheme, ipAddress, h.Port, h.GetPath()))
+	return h.pathCheck(ctx, client, fmt.Sprintf("%s://%s:%d%s", scheme, ipAddress, h.Port, h.GetPath()))
+}
+
+func (h Healthcheck) pathCheck(ctx context.Context, client *resty.Client, url string) ([]byte, error) {
+	resp, err := client.R().SetContext(ctx).Get(url)
 	if err != nil {
-		return []byte{}, []error{err}
+		return []byte{}, err
 	}
 
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return []byte{}, []error{fmt.Errorf("unable to read response body: %w", err)}
+		return []byte{}, fmt.Errorf("unable to read response body: %w", err)
 	}
 
 	if !resp.IsStatusSuccess() {
-		return body, []error{fmt.Errorf("unexpected status code: %d", resp.StatusCode())}
+		return body, fmt.Errorf("unexpected status code: %d", resp.StatusCode())
 	}
 
 	if h.Content != "" && !bytes.Contains(body, []byte(h.Content)) {
-		return body, []error{fmt.Errorf("unable to find expected content in response body: %s", h.Content)}
+		return body, fmt.Errorf("unable to find expected content in response body: %s", h.Content)
 	}
 
-	return body, []error{}
+	return body, nil
 }
 
-func (h Healthcheck) executeUptimeCheck(container container_types.InspectResponse) ([]byte, []error) {
-	tt, err := time.Parse(time.RFC3339Nano, container.State.StartedAt)
-	if err != nil {
-		return []byte{}, []error{err}
-	}
-	delay := 0
-	uptime := int(time.Since(tt).Seconds())
-	if uptime < h.Uptime {
-		delay = h.Uptime - uptime
-	}
+type uptimeChecker struct{}
 
-	if delay > 0 {
-		time.Sleep(time.Duration(delay) * time.Second)
-	}
+func (uptimeChecker) Configured(h Healthcheck) bool {
+	return h.Uptime > 0
+}
 
-	cli, err := client.NewClientWithOpts(
-		client.FromEnv,
-		client.WithAPIVersionNegotiation(),
-	)
-	if err != nil {
-		return []byte{}, []error{err}
-	}
+func (uptimeChecker) Validate(h Healthcheck) error {
+	return nil
+}
+
+func (uptimeChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("type='uptime' uptime=%d", h.Uptime)
+}
+
+func (uptimeChecker) startDelay(h Healthcheck, uptime time.Duration) time.Duration {
+	return max(time.Duration(h.Uptime)*time.Second-uptime, 0)
+}
+
+func (uptimeChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return withDockerClient(hctx, func(cli *client.Client) ([]byte, error) {
+		return h.uptimeCheck(ctx, cli, container)
+	})
+}
 
-	inspect, err := cli.ContainerInspect(context.Background(), container.ID, client.ContainerInspectOptions{})
+func (h Healthcheck) uptimeCheck(ctx context.Context, cli *client.Client, container container_types.InspectResponse) ([]byte, error) {
+	inspect, err := cli.ContainerInspect(ctx, container.ID, client.ContainerInspectOptions{})
 	if err != nil {
-		return []byte{}, []error{err}
+		return []byte{}, err
 	}
 	container = inspect.Container
 
 	status := fmt.Sprintf("state=%s", container.State.Status)
 	if !container.State.Running {
-		return []byte(status), []error{errors.New("container state is not running")}
+		output := h.uptimeFailureOutput(ctx, cli, container, status)
+		return output, fmt.Errorf("container state is not running: %s", describeExit(container.State))
+	}
+
+	// the restart count never goes down, so later attempts cannot pass
+	if container.RestartCount > h.MaxRestarts {
+		output := h.uptimeFailureOutput(ctx, cli, container, status)
+		if h.MaxRestarts > 0 {
+			return output, Unrecoverable(fmt.Errorf("container has restarted %d times, more than the allowed %d restarts", container.RestartCount, h.MaxRestarts))
+		}
+		return output, Unrecoverable(fmt.Errorf("container has restarted %d times", container.RestartCount))
 	}
 
-	if container.RestartCount > 0 {
-		return []byte(status), []error{fmt.Errorf("container has restarted %d times", container.RestartCount)}
+	return []byte(status), nil
+}
+
+// describeExit summarizes why a container is no longer running
+func describeExit(state *container_types.State) string {
+	parts := []string{
+		fmt.Sprintf("state=%s", state.Status),
+		fmt.Sprintf("exit_code=%d", state.ExitCode),
+		fmt.Sprintf("oom_killed=%t", state.OOMKilled),
+	}
+	if state.FinishedAt != "" {
+		parts = append(parts, fmt.Sprintf("finished_at=%s", state.FinishedAt))
+	}
+	if state.Error != "" {
+		parts = append(parts, fmt.Sprintf("error='%s'", state.Error))
 	}
 
-	return []byte(status), []error{}
+	return strings.Join(parts, " ")
 }
 
-func (h Healthcheck) executeListenerCheck(container container_types.InspectResponse) ([]byte, []error) {
-	err := retry.Do(
-		func() error {
-			return h.listeningCheck(container)
-		},
-		retry.Attempts(uint(h.GetAttempts())),
-		retry.Delay(time.Duration(h.GetWait())*time.Second),
-	)
+// uptimeFailureOutput appends the last logLines lines of container output to the status
+func (h Healthcheck) uptimeFailureOutput(ctx context.Context, cli *client.Client, container container_types.InspectResponse, status string) []byte {
+	if h.LogLines <= 0 {
+		return []byte(status)
+	}
+
+	lines := []string{status}
 
+	logs, err := containerLogs(ctx, cli, container, strconv.Itoa(h.LogLines))
 	if err != nil {
-		return []byte{}, err.(retry.Error).WrappedErrors()
+		lines = append(lines, err.Error())
+		return []byte(strings.Join(lines, "\n"))
 	}
 
-	return []byte{}, nil
+	lines = append(lines, fmt.Sprintf("last %d log lines:", h.LogLines))
+	lines = append(lines, logs...)
+	return []byte(strings.Join(lines, "\n"))
+}
+
+type listeningChecker struct{}
+
+func (listeningChecker) Configured(h Healthcheck) bool {
+	return h.Listening
 }
 
-func (h Healthcheck) listeningCheck(container container_types.InspectResponse) error {
+func (listeningChecker) Validate(h Healthcheck) error {
+	return nil
+}
+
+func (listeningChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("attempts=%d port=%d retries=%d timeout=%d type='listening' wait=%d", h.GetAttempts(), h.Port, h.GetRetries(), h.GetTimeout(), h.GetWait())
+}
+
+func (listeningChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return []byte{}, h.listeningCheck(ctx, container)
+}
+
+func (h Healthcheck) listeningCheck(ctx context.Context, container container_types.InspectResponse) error {
 	if !container.State.Running {
 		return errors.New("container state is not running")
 	}
@@ -518,13 +995,6 @@ func (h Healthcheck) listeningCheck(container container_types.InspectResponse) e
 		return errors.New("container state is not running")
 	}
 
-	ctx := context.Background()
-	if h.GetTimeout() > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(h.GetTimeout())*time.Second)
-		defer cancel()
-	}
-
 	cmd := execute.ExecTask{
 		Command:     "nsenter",
 		Args:        []string{"-t", fmt.Sprint(container.State.Pid), "-n", "netstat", "-plant"},
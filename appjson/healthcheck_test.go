@@ -1,6 +1,10 @@
 package appjson
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+)
 
 func TestHealthcheck_validateAddresses(t *testing.T) {
 	type fields struct {
@@ -38,3 +42,338 @@ func TestHealthcheck_validateAddresses(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthcheck_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthcheck Healthcheck
+		wantErr     bool
+	}{
+		{
+			name:        "when only uptime is set",
+			healthcheck: Healthcheck{Uptime: 10},
+			wantErr:     false,
+		},
+		{
+			name:        "when only dockerHealth is set",
+			healthcheck: Healthcheck{DockerHealth: true},
+			wantErr:     false,
+		},
+		{
+			name:        "when maxRestarts is negative",
+			healthcheck: Healthcheck{Uptime: 10, MaxRestarts: -1},
+			wantErr:     true,
+		},
+		{
+			name:        "when command has an exec wrapper",
+			healthcheck: Healthcheck{Command: []string{"true"}, ExecWrapper: "cnb"},
+			wantErr:     false,
+		},
+		{
+			name:        "when command has an invalid exec wrapper",
+			healthcheck: Healthcheck{Command: []string{"true"}, ExecWrapper: "supervisor"},
+			wantErr:     true,
+		},
+		{
+			name:        "when only hostCommand is set",
+			healthcheck: Healthcheck{HostCommand: []string{"true"}},
+			wantErr:     false,
+		},
+		{
+			name:        "when the command strategy is set without its field",
+			healthcheck: Healthcheck{Strategy: "command"},
+			wantErr:     true,
+		},
+		{
+			name:        "when the hostCommand strategy is set without its field",
+			healthcheck: Healthcheck{Strategy: "hostCommand"},
+			wantErr:     true,
+		},
+		{
+			name:        "when the logs strategy is set without its field",
+			healthcheck: Healthcheck{Strategy: "logs"},
+			wantErr:     true,
+		},
+		{
+			name:        "when the resources strategy is set without its field",
+			healthcheck: Healthcheck{Strategy: "resources"},
+			wantErr:     true,
+		},
+		{
+			name:        "when the zombies strategy is set without its field",
+			healthcheck: Healthcheck{Strategy: "zombies"},
+			wantErr:     true,
+		},
+		{
+			name:        "when the process strategy is set without its field",
+			healthcheck: Healthcheck{Strategy: "process"},
+			wantErr:     true,
+		},
+		{
+			name:        "when the file strategy is set without its field",
+			healthcheck: Healthcheck{Strategy: "file"},
+			wantErr:     true,
+		},
+		{
+			name:        "when the env strategy is set without its field",
+			healthcheck: Healthcheck{Strategy: "env"},
+			wantErr:     true,
+		},
+		{
+			name:        "when command and hostCommand are set",
+			healthcheck: Healthcheck{Command: []string{"true"}, HostCommand: []string{"true"}},
+			wantErr:     true,
+		},
+		{
+			name:        "when hostCommand has exec options",
+			healthcheck: Healthcheck{HostCommand: []string{"true"}, User: "app"},
+			wantErr:     true,
+		},
+		{
+			name:        "when command has a nagios format",
+			healthcheck: Healthcheck{Command: []string{"true"}, Format: "nagios"},
+			wantErr:     false,
+		},
+		{
+			name:        "when format is invalid",
+			healthcheck: Healthcheck{Command: []string{"true"}, Format: "json"},
+			wantErr:     true,
+		},
+		{
+			name:        "when nagios format is set with exit code mappings",
+			healthcheck: Healthcheck{Command: []string{"true"}, Format: "nagios", WarnExitCodes: []int{1}},
+			wantErr:     true,
+		},
+		{
+			name:        "when command has exit code mappings",
+			healthcheck: Healthcheck{Command: []string{"true"}, SuccessExitCodes: []int{0, 3}, WarnExitCodes: []int{1}},
+			wantErr:     false,
+		},
+		{
+			name:        "when exit code mappings are set without a command",
+			healthcheck: Healthcheck{Uptime: 10, WarnExitCodes: []int{1}},
+			wantErr:     true,
+		},
+		{
+			name:        "when an exit code is both success and warn",
+			healthcheck: Healthcheck{Command: []string{"true"}, WarnExitCodes: []int{0}},
+			wantErr:     true,
+		},
+		{
+			name:        "when an exit code is out of range",
+			healthcheck: Healthcheck{Command: []string{"true"}, SuccessExitCodes: []int{256}},
+			wantErr:     true,
+		},
+		{
+			name:        "when command has exec options",
+			healthcheck: Healthcheck{Command: []string{"true"}, User: "app", WorkingDir: "/app", Env: []string{"CHECK=1"}},
+			wantErr:     false,
+		},
+		{
+			name:        "when exec options are set without a command",
+			healthcheck: Healthcheck{Path: "/", User: "app"},
+			wantErr:     true,
+		},
+		{
+			name:        "when command has an invalid env value",
+			healthcheck: Healthcheck{Command: []string{"true"}, Env: []string{"CHECK"}},
+			wantErr:     true,
+		},
+		{
+			name:        "when command has a relative working directory",
+			healthcheck: Healthcheck{Command: []string{"true"}, WorkingDir: "app"},
+			wantErr:     true,
+		},
+		{
+			name:        "when command and path are set",
+			healthcheck: Healthcheck{Command: []string{"true"}, Path: "/"},
+			wantErr:     true,
+		},
+		{
+			name:        "when logs has a ready pattern",
+			healthcheck: Healthcheck{Logs: &LogPatterns{ReadyPattern: "Listening on port \\d+", FailPatterns: []string{"FATAL"}}},
+			wantErr:     false,
+		},
+		{
+			name:        "when logs is missing a ready pattern",
+			healthcheck: Healthcheck{Logs: &LogPatterns{FailPatterns: []string{"FATAL"}}},
+			wantErr:     true,
+		},
+		{
+			name:        "when logs has an invalid fail pattern",
+			healthcheck: Healthcheck{Logs: &LogPatterns{ReadyPattern: "ready", FailPatterns: []string{"("}}},
+			wantErr:     true,
+		},
+		{
+			name:        "when dockerHealth and uptime are set",
+			healthcheck: Healthcheck{DockerHealth: true, Uptime: 10},
+			wantErr:     true,
+		},
+		{
+			name:        "when using an exponential backoff",
+			healthcheck: Healthcheck{Path: "/", Backoff: "exponential", MaxWait: 30, Jitter: 1},
+			wantErr:     false,
+		},
+		{
+			name:        "when backoff is invalid",
+			healthcheck: Healthcheck{Path: "/", Backoff: "linear"},
+			wantErr:     true,
+		},
+		{
+			name:        "when maxWait is set without an exponential backoff",
+			healthcheck: Healthcheck{Path: "/", MaxWait: 30},
+			wantErr:     true,
+		},
+		{
+			name:        "when the success threshold exceeds the attempts",
+			healthcheck: Healthcheck{Path: "/", Attempts: 2, SuccessThreshold: 3},
+			wantErr:     true,
+		},
+		{
+			name:        "when the failure threshold exceeds the attempts",
+			healthcheck: Healthcheck{Path: "/", Attempts: 2, FailureThreshold: 3},
+			wantErr:     true,
+		},
+		{
+			name:        "when jitter is negative",
+			healthcheck: Healthcheck{Path: "/", Jitter: -1},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.healthcheck.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Healthcheck.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHealthcheck_mapExitCode(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthcheck Healthcheck
+		err         error
+		wantErr     string
+		wantWarning bool
+	}{
+		{
+			name:        "when no mappings are set",
+			healthcheck: Healthcheck{},
+			err:         exitCodeError{ExitCode: 1},
+			wantErr:     "non-zero exit code 1",
+		},
+		{
+			name:        "when exit code is mapped to success",
+			healthcheck: Healthcheck{SuccessExitCodes: []int{0, 3}},
+			err:         exitCodeError{ExitCode: 3},
+		},
+		{
+			name:        "when exit code is mapped to warning",
+			healthcheck: Healthcheck{WarnExitCodes: []int{1}},
+			err:         exitCodeError{ExitCode: 1},
+			wantErr:     "exit code 1 mapped to warning",
+			wantWarning: true,
+		},
+		{
+			name:        "when exit code is not mapped",
+			healthcheck: Healthcheck{WarnExitCodes: []int{1}},
+			err:         exitCodeError{ExitCode: 2},
+			wantErr:     "non-zero exit code 2 mapped to failure",
+		},
+		{
+			name:        "when exit code zero is not a success code",
+			healthcheck: Healthcheck{SuccessExitCodes: []int{1}},
+			wantErr:     "exit code 0 mapped to failure",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.healthcheck.mapExitCode([]byte{}, tt.err)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Healthcheck.mapExitCode() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Healthcheck.mapExitCode() error = %v, want %s", err, tt.wantErr)
+				return
+			}
+
+			if IsWarning(err) != tt.wantWarning {
+				t.Errorf("IsWarning() = %v, want %v", IsWarning(err), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestHealthcheck_GetMaxDuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthcheck Healthcheck
+		want        time.Duration
+	}{
+		{
+			name:        "when using the defaults",
+			healthcheck: Healthcheck{Uptime: 10},
+			want:        35 * time.Second,
+		},
+		{
+			name:        "when retrying a command",
+			healthcheck: Healthcheck{Command: []string{"true"}, Attempts: 3, Timeout: 5, Wait: 2, InitialDelay: 10},
+			want:        29 * time.Second,
+		},
+		{
+			name:        "when sampling cpu usage",
+			healthcheck: Healthcheck{Resources: &ResourceLimits{MaxCPUPercent: 90, SampleSeconds: 2}, Attempts: 2, Timeout: 3, Wait: 1},
+			want:        17 * time.Second,
+		},
+		{
+			name:        "when checking the environment",
+			healthcheck: Healthcheck{EnvAssertions: []EnvAssertion{{Name: "PORT"}}, Attempts: 1, InitialDelay: 4},
+			want:        9 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.healthcheck.GetMaxDuration(); got != tt.want {
+				t.Errorf("Healthcheck.GetMaxDuration() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	buffer := &cappedBuffer{limit: 5}
+	n, err := buffer.Write([]byte("hello world"))
+	if err != nil || n != 11 {
+		t.Fatalf("cappedBuffer.Write() = %d, %v, want 11, nil", n, err)
+	}
+
+	if _, err := buffer.Write([]byte("more")); err != nil {
+		t.Fatalf("cappedBuffer.Write() error = %v", err)
+	}
+
+	want := "hello\n[output truncated to 5 bytes]"
+	if got := string(buffer.Bytes()); got != want {
+		t.Errorf("cappedBuffer.Bytes() = %q, want %q", got, want)
+	}
+}
+
+func TestHealthcheck_HandleFailureCancelled(t *testing.T) {
+	h := Healthcheck{OnFailure: &OnFailure{Command: []string{"sleep", "10"}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := h.HandleFailure(ctx, []error{}); err == nil {
+		t.Errorf("Healthcheck.HandleFailure() expected error for a cancelled hook")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Healthcheck.HandleFailure() took %s after cancellation", elapsed)
+	}
+}
@@ -0,0 +1,74 @@
+package appjson
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alexellis/go-execute/v2"
+	container_types "github.com/moby/moby/api/types/container"
+)
+
+type hostCommandChecker struct{}
+
+func (hostCommandChecker) Configured(h Healthcheck) bool {
+	return len(h.HostCommand) > 0
+}
+
+func (hostCommandChecker) Validate(h Healthcheck) error {
+	if len(h.HostCommand) == 0 {
+		return fmt.Errorf("healthcheck name='%s' requires a 'hostCommand' value for the 'hostCommand' strategy", h.GetName())
+	}
+
+	return nil
+}
+
+func (hostCommandChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("attempts=%d hostCommand='%s' timeout=%d type='hostCommand' wait=%d", h.GetAttempts(), h.HostCommand, h.GetTimeout(), h.GetWait())
+}
+
+func (hostCommandChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return h.hostCommandCheck(ctx, container, hctx)
+}
+
+func (h Healthcheck) hostCommandCheck(ctx context.Context, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	stdout := &cappedBuffer{limit: maxCommandOutputSize}
+	stderr := &cappedBuffer{limit: maxCommandOutputSize}
+	cmd := execute.ExecTask{
+		Command:            h.HostCommand[0],
+		Args:               h.HostCommand[1:],
+		Env:                h.hostCommandEnv(container, hctx),
+		DisableStdioBuffer: true,
+		StdOutWriter:       stdout,
+		StdErrWriter:       stderr,
+		StreamStdio:        false,
+	}
+	result, err := cmd.Execute(ctx)
+	b := commandOutput(stdout, stderr)
+	if err != nil {
+		return b, fmt.Errorf("unable to execute host command: %w", err)
+	}
+
+	if result.ExitCode != 0 {
+		return b, exitCodeError{ExitCode: result.ExitCode}
+	}
+
+	return b, nil
+}
+
+// hostCommandEnv returns the environment variables describing the
+// target container that are passed to a host command
+func (h Healthcheck) hostCommandEnv(container container_types.InspectResponse, ctx HealthcheckContext) []string {
+	// the ip address is informational, so a container that is not
+	// attached to the network still runs the command without one
+	ipAddress, _ := containerIPAddress(container, ctx)
+
+	return []string{
+		fmt.Sprintf("HEALTHCHECK_CONTAINER_ID=%s", container.ID),
+		fmt.Sprintf("HEALTHCHECK_CONTAINER_IP=%s", ipAddress),
+		fmt.Sprintf("HEALTHCHECK_CONTAINER_NAME=%s", strings.TrimPrefix(container.Name, "/")),
+		fmt.Sprintf("HEALTHCHECK_NAME=%s", h.GetName()),
+		fmt.Sprintf("HEALTHCHECK_PORT=%d", h.Port),
+		fmt.Sprintf("HEALTHCHECK_PROCESS_TYPE=%s", ctx.ProcessType),
+	}
+}
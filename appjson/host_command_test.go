@@ -0,0 +1,71 @@
+package appjson
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	container_types "github.com/moby/moby/api/types/container"
+)
+
+func TestHealthcheck_hostCommandEnv(t *testing.T) {
+	h := Healthcheck{Name: "smoke test", Port: 5000}
+	container := container_types.InspectResponse{ID: "abc123", Name: "/app.web.1"}
+	ctx := HealthcheckContext{IPAddress: "10.0.0.2", ProcessType: "web"}
+
+	env := h.hostCommandEnv(container, ctx)
+	want := []string{
+		"HEALTHCHECK_CONTAINER_ID=abc123",
+		"HEALTHCHECK_CONTAINER_IP=10.0.0.2",
+		"HEALTHCHECK_CONTAINER_NAME=app.web.1",
+		"HEALTHCHECK_NAME=smoke test",
+		"HEALTHCHECK_PORT=5000",
+		"HEALTHCHECK_PROCESS_TYPE=web",
+	}
+	if !slices.Equal(env, want) {
+		t.Errorf("Healthcheck.hostCommandEnv() = %v, want %v", env, want)
+	}
+}
+
+func TestHealthcheck_hostCommandCheck(t *testing.T) {
+	h := Healthcheck{HostCommand: []string{"sh", "-c", "echo $HEALTHCHECK_PROCESS_TYPE; exit 2"}, Timeout: 5}
+	ctx := HealthcheckContext{IPAddress: "10.0.0.2", ProcessType: "worker"}
+
+	b, err := h.hostCommandCheck(context.Background(), container_types.InspectResponse{}, ctx)
+	if err == nil || err.Error() != "non-zero exit code 2" {
+		t.Errorf("Healthcheck.hostCommandCheck() error = %v, want non-zero exit code 2", err)
+	}
+
+	if string(b) != "worker\n" {
+		t.Errorf("Healthcheck.hostCommandCheck() output = %q, want %q", string(b), "worker\n")
+	}
+}
+
+func TestHealthcheck_ExecuteCancelled(t *testing.T) {
+	h := Healthcheck{HostCommand: []string{"sleep", "10"}, Attempts: 3, Timeout: 30}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, errs := h.Execute(ctx, container_types.InspectResponse{}, HealthcheckContext{IPAddress: "10.0.0.2"})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Healthcheck.Execute() took %s after cancellation", elapsed)
+	}
+
+	if len(errs) == 0 || !errors.Is(errs[len(errs)-1], ErrCancelled) {
+		t.Errorf("Healthcheck.Execute() errors = %v, want ErrCancelled", errs)
+	}
+}
+
+func TestHealthcheck_ExecuteTimedOut(t *testing.T) {
+	h := Healthcheck{HostCommand: []string{"sleep", "10"}, Attempts: 3, Timeout: 30}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, errs := h.Execute(ctx, container_types.InspectResponse{}, HealthcheckContext{IPAddress: "10.0.0.2"})
+	if len(errs) == 0 || !errors.Is(errs[len(errs)-1], ErrTimedOut) {
+		t.Errorf("Healthcheck.Execute() errors = %v, want ErrTimedOut", errs)
+	}
+}
@@ -0,0 +1,150 @@
+package appjson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+type LogPatterns struct {
+	FailPatterns []string `json:"failPatterns,omitempty"`
+	ReadyPattern string   `json:"readyPattern,omitempty"`
+}
+
+func (l LogPatterns) Validate() error {
+	if l.ReadyPattern == "" {
+		return errors.New("missing 'readyPattern' value")
+	}
+
+	if _, err := regexp.Compile(l.ReadyPattern); err != nil {
+		return fmt.Errorf("invalid 'readyPattern' value: %w", err)
+	}
+
+	for _, pattern := range l.FailPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid 'failPatterns' value: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type logsChecker struct{}
+
+func (logsChecker) Configured(h Healthcheck) bool {
+	return h.Logs != nil
+}
+
+func (logsChecker) Validate(h Healthcheck) error {
+	if h.Logs == nil {
+		return fmt.Errorf("healthcheck name='%s' requires a 'logs' value for the 'logs' strategy", h.GetName())
+	}
+
+	if err := h.Logs.Validate(); err != nil {
+		return fmt.Errorf("healthcheck name='%s' has an invalid 'logs' value: %w", h.GetName(), err)
+	}
+
+	return nil
+}
+
+func (logsChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("attempts=%d readyPattern='%s' timeout=%d type='logs' wait=%d", h.GetAttempts(), h.Logs.ReadyPattern, h.GetTimeout(), h.GetWait())
+}
+
+func (logsChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	readyPattern, err := regexp.Compile(h.Logs.ReadyPattern)
+	if err != nil {
+		return []byte{}, Unrecoverable(err)
+	}
+
+	failPatterns := []*regexp.Regexp{}
+	for _, pattern := range h.Logs.FailPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return []byte{}, Unrecoverable(err)
+		}
+		failPatterns = append(failPatterns, re)
+	}
+
+	return withDockerClient(hctx, func(cli *client.Client) ([]byte, error) {
+		return h.logsCheck(ctx, cli, container, readyPattern, failPatterns)
+	})
+}
+
+func (h Healthcheck) logsCheck(ctx context.Context, cli *client.Client, container container_types.InspectResponse, readyPattern *regexp.Regexp, failPatterns []*regexp.Regexp) ([]byte, error) {
+	lines, err := containerLogs(ctx, cli, container, "")
+	if err != nil {
+		return []byte{}, err
+	}
+
+	failLines := []string{}
+	readyLines := []string{}
+	for _, line := range lines {
+		for _, re := range failPatterns {
+			if re.MatchString(line) {
+				failLines = append(failLines, line)
+				break
+			}
+		}
+
+		if readyPattern.MatchString(line) {
+			readyLines = append(readyLines, line)
+		}
+	}
+
+	if len(failLines) > 0 {
+		return []byte(strings.Join(failLines, "\n")), Unrecoverable(fmt.Errorf("found fail pattern in container logs %d times", len(failLines)))
+	}
+
+	if len(readyLines) == 0 {
+		return []byte{}, fmt.Errorf("unable to find ready pattern in container logs: %s", readyPattern.String())
+	}
+
+	return []byte(strings.Join(readyLines, "\n")), nil
+}
+
+// containerLogs returns the stdout and stderr lines logged by the container
+// since it was last started, optionally limited to the last tail lines
+func containerLogs(ctx context.Context, cli *client.Client, container container_types.InspectResponse, tail string) ([]string, error) {
+	reader, err := cli.ContainerLogs(ctx, container.ID, client.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Since:      container.State.StartedAt,
+		Tail:       tail,
+	})
+	if err != nil {
+		return []string{}, fmt.Errorf("unable to fetch container logs: %w", err)
+	}
+	defer reader.Close()
+
+	var buffer bytes.Buffer
+	if container.Config != nil && container.Config.Tty {
+		_, err = io.Copy(&buffer, reader)
+	} else {
+		_, err = stdcopy.StdCopy(&buffer, &buffer, reader)
+	}
+	if err != nil {
+		return []string{}, fmt.Errorf("unable to read container logs: %w", err)
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(&buffer)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return []string{}, fmt.Errorf("unable to read container logs: %w", err)
+	}
+
+	return lines, nil
+}
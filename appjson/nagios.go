@@ -0,0 +1,146 @@
+package appjson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// nagiosStates maps nagios plugin exit codes to their service state
+var nagiosStates = map[int]string{
+	0: "OK",
+	1: "WARNING",
+	2: "CRITICAL",
+	3: "UNKNOWN",
+}
+
+type Perfdata struct {
+	Critical string  `json:"critical,omitempty"`
+	Label    string  `json:"label"`
+	Max      string  `json:"max,omitempty"`
+	Min      string  `json:"min,omitempty"`
+	Unit     string  `json:"unit,omitempty"`
+	Value    float64 `json:"value"`
+	Warning  string  `json:"warning,omitempty"`
+}
+
+func (p Perfdata) String() string {
+	return fmt.Sprintf("label='%s' value=%s%s warning='%s' critical='%s' min='%s' max='%s'", p.Label, strconv.FormatFloat(p.Value, 'f', -1, 64), p.Unit, p.Warning, p.Critical, p.Min, p.Max)
+}
+
+// ParseNagiosOutput splits nagios plugin output into the status text
+// and the performance data found after any '|' separator
+func ParseNagiosOutput(b []byte) (string, []Perfdata) {
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	text, perf, inPerf := "", []string{}, false
+	for i, line := range lines {
+		if inPerf {
+			perf = append(perf, line)
+			continue
+		}
+
+		before, after, found := strings.Cut(line, "|")
+		if i == 0 {
+			text = strings.TrimSpace(before)
+		}
+		if found {
+			perf = append(perf, after)
+			// perfdata after the first line runs until the end of the output
+			inPerf = i > 0
+		}
+	}
+
+	perfdata := []Perfdata{}
+	for _, field := range splitPerfdata(strings.Join(perf, " ")) {
+		if p, err := parsePerfdata(field); err == nil {
+			perfdata = append(perfdata, p)
+		}
+	}
+
+	return text, perfdata
+}
+
+// splitPerfdata splits perfdata on whitespace, keeping quoted labels intact
+func splitPerfdata(s string) []string {
+	fields := []string{}
+	var field strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			field.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
+
+func parsePerfdata(field string) (Perfdata, error) {
+	index := strings.LastIndex(field, "=")
+	if index <= 0 {
+		return Perfdata{}, fmt.Errorf("invalid perfdata: %s", field)
+	}
+
+	label := strings.ReplaceAll(strings.Trim(field[:index], "'"), "''", "'")
+	values := strings.Split(field[index+1:], ";")
+
+	raw := values[0]
+	end := len(raw)
+	for end > 0 && !strings.ContainsRune("0123456789.-", rune(raw[end-1])) {
+		end--
+	}
+
+	value, err := strconv.ParseFloat(raw[:end], 64)
+	if err != nil {
+		return Perfdata{}, fmt.Errorf("invalid perfdata value for label '%s': %w", label, err)
+	}
+
+	p := Perfdata{Label: label, Value: value, Unit: raw[end:]}
+	for i, target := range []*string{&p.Warning, &p.Critical, &p.Min, &p.Max} {
+		if i+1 < len(values) {
+			*target = values[i+1]
+		}
+	}
+
+	return p, nil
+}
+
+// mapNagiosResult maps a nagios plugin exit code to a passing,
+// warning or failing result, reporting the plugin status text
+func mapNagiosResult(b []byte, err error) ([]byte, error) {
+	exitCode := 0
+	var exitErr exitCodeError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode
+	} else if err != nil {
+		return b, err
+	}
+
+	state, ok := nagiosStates[exitCode]
+	if !ok {
+		return b, fmt.Errorf("non-zero exit code %d is not a valid nagios plugin state", exitCode)
+	}
+
+	text, _ := ParseNagiosOutput(b)
+	switch exitCode {
+	case 0:
+		return b, nil
+	case 1:
+		return b, WarningError{Err: fmt.Errorf("nagios state %s: %s", state, text)}
+	default:
+		return b, fmt.Errorf("nagios state %s: %s", state, text)
+	}
+}
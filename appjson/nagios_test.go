@@ -0,0 +1,83 @@
+package appjson
+
+import "testing"
+
+func TestParseNagiosOutput(t *testing.T) {
+	output := "DISK OK - free space: / 3326 MB (56%); | /=2643MB;5948;5958;0;5968\nlong output line\n| 'inode usage'=12%;80;90 time=0.5s"
+
+	text, perfdata := ParseNagiosOutput([]byte(output))
+	if text != "DISK OK - free space: / 3326 MB (56%);" {
+		t.Errorf("ParseNagiosOutput() text = %q", text)
+	}
+
+	if len(perfdata) != 3 {
+		t.Fatalf("ParseNagiosOutput() returned %d perfdata, want 3: %+v", len(perfdata), perfdata)
+	}
+
+	want := Perfdata{Label: "/", Value: 2643, Unit: "MB", Warning: "5948", Critical: "5958", Min: "0", Max: "5968"}
+	if perfdata[0] != want {
+		t.Errorf("ParseNagiosOutput() perfdata[0] = %+v, want %+v", perfdata[0], want)
+	}
+
+	if perfdata[1].Label != "inode usage" || perfdata[1].Value != 12 || perfdata[1].Unit != "%" {
+		t.Errorf("ParseNagiosOutput() perfdata[1] = %+v", perfdata[1])
+	}
+
+	if perfdata[2].Label != "time" || perfdata[2].Value != 0.5 || perfdata[2].Unit != "s" {
+		t.Errorf("ParseNagiosOutput() perfdata[2] = %+v", perfdata[2])
+	}
+}
+
+func TestMapNagiosResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantErr     string
+		wantWarning bool
+	}{
+		{
+			name: "when the state is OK",
+		},
+		{
+			name:        "when the state is WARNING",
+			err:         exitCodeError{ExitCode: 1},
+			wantErr:     "nagios state WARNING: CHECK OK",
+			wantWarning: true,
+		},
+		{
+			name:    "when the state is CRITICAL",
+			err:     exitCodeError{ExitCode: 2},
+			wantErr: "nagios state CRITICAL: CHECK OK",
+		},
+		{
+			name:    "when the state is UNKNOWN",
+			err:     exitCodeError{ExitCode: 3},
+			wantErr: "nagios state UNKNOWN: CHECK OK",
+		},
+		{
+			name:    "when the exit code is not a nagios state",
+			err:     exitCodeError{ExitCode: 4},
+			wantErr: "non-zero exit code 4 is not a valid nagios plugin state",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := mapNagiosResult([]byte("CHECK OK | time=1s"), tt.err)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("mapNagiosResult() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("mapNagiosResult() error = %v, want %s", err, tt.wantErr)
+				return
+			}
+
+			if IsWarning(err) != tt.wantWarning {
+				t.Errorf("IsWarning() = %v, want %v", IsWarning(err), tt.wantWarning)
+			}
+		})
+	}
+}
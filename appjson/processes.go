@@ -0,0 +1,213 @@
+package appjson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+type ProcessMatcher struct {
+	Max     int    `json:"max,omitempty"`
+	Min     *int   `json:"min,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+type containerProcess struct {
+	Command string
+	PID     int
+	PPID    int
+	State   string
+}
+
+// GetMin returns the minimum number of matching processes, which
+// defaults to 1 and may be set to 0 to only limit the maximum
+func (p ProcessMatcher) GetMin() int {
+	if p.Min == nil {
+		return 1
+	}
+
+	return *p.Min
+}
+
+func (p ProcessMatcher) String() string {
+	if p.Name != "" {
+		return fmt.Sprintf("name='%s'", p.Name)
+	}
+
+	return fmt.Sprintf("pattern='%s'", p.Pattern)
+}
+
+func (p ProcessMatcher) Validate() error {
+	if p.Name == "" && p.Pattern == "" {
+		return errors.New("process matcher must contain either a 'name' or a 'pattern' value")
+	}
+
+	if p.Name != "" && p.Pattern != "" {
+		return errors.New("process matcher cannot contain both a 'name' and a 'pattern' value")
+	}
+
+	if p.Pattern != "" {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("invalid 'pattern' value: %w", err)
+		}
+	}
+
+	if p.GetMin() < 0 || p.Max < 0 {
+		return errors.New("process matcher cannot contain a negative 'min' or 'max' value")
+	}
+
+	if p.Max > 0 && p.Max < p.GetMin() {
+		return fmt.Errorf("process matcher 'max' value %d is less than the 'min' value %d", p.Max, p.GetMin())
+	}
+
+	return nil
+}
+
+// compile returns a function reporting whether a process command line
+// matches, so a pattern is only compiled once per attempt
+func (p ProcessMatcher) compile() (func(containerProcess) bool, error) {
+	if p.Pattern != "" {
+		pattern, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'pattern' value: %w", err)
+		}
+
+		return func(process containerProcess) bool {
+			return pattern.MatchString(process.Command)
+		}, nil
+	}
+
+	return func(process containerProcess) bool {
+		fields := strings.Fields(process.Command)
+		if len(fields) == 0 {
+			return false
+		}
+
+		return filepath.Base(fields[0]) == p.Name
+	}, nil
+}
+
+type processChecker struct{}
+
+func (processChecker) Configured(h Healthcheck) bool {
+	return len(h.Processes) > 0
+}
+
+func (processChecker) Validate(h Healthcheck) error {
+	if len(h.Processes) == 0 {
+		return fmt.Errorf("healthcheck name='%s' requires a 'processes' value for the 'process' strategy", h.GetName())
+	}
+
+	for _, matcher := range h.Processes {
+		if err := matcher.Validate(); err != nil {
+			return fmt.Errorf("healthcheck name='%s' has an invalid 'processes' value: %w", h.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (processChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("attempts=%d processes=%d timeout=%d type='process' wait=%d", h.GetAttempts(), len(h.Processes), h.GetTimeout(), h.GetWait())
+}
+
+func (processChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return withDockerClient(hctx, func(cli *client.Client) ([]byte, error) {
+		return h.processCheck(ctx, cli, container)
+	})
+}
+
+func (h Healthcheck) processCheck(ctx context.Context, cli *client.Client, container container_types.InspectResponse) ([]byte, error) {
+	processes, err := containerProcesses(ctx, cli, container)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	lines := []string{}
+	failures := []string{}
+	for _, matcher := range h.Processes {
+		matches, err := matcher.compile()
+		if err != nil {
+			return []byte{}, Unrecoverable(err)
+		}
+
+		count := 0
+		for _, process := range processes {
+			if matches(process) {
+				count++
+			}
+		}
+
+		lines = append(lines, fmt.Sprintf("%s count=%d min=%d max=%d", matcher.String(), count, matcher.GetMin(), matcher.Max))
+		if count < matcher.GetMin() {
+			failures = append(failures, fmt.Sprintf("expected at least %d processes matching %s, found %d", matcher.GetMin(), matcher.String(), count))
+		} else if matcher.Max > 0 && count > matcher.Max {
+			failures = append(failures, fmt.Sprintf("expected at most %d processes matching %s, found %d", matcher.Max, matcher.String(), count))
+		}
+	}
+
+	if len(failures) > 0 {
+		return []byte(strings.Join(lines, "\n")), errors.New(strings.Join(failures, ", "))
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// containerProcesses lists the processes running in the container via the docker top api
+func containerProcesses(ctx context.Context, cli *client.Client, container container_types.InspectResponse) ([]containerProcess, error) {
+	top, err := cli.ContainerTop(ctx, container.ID, client.ContainerTopOptions{
+		Arguments: []string{"-eo", "pid,ppid,stat,args"},
+	})
+	if err != nil {
+		return []containerProcess{}, fmt.Errorf("unable to list container processes: %w", err)
+	}
+
+	return parseContainerProcesses(top.Titles, top.Processes)
+}
+
+func parseContainerProcesses(titles []string, rows [][]string) ([]containerProcess, error) {
+	columns := map[string]int{}
+	for i, title := range titles {
+		columns[strings.ToUpper(title)] = i
+	}
+
+	for _, title := range []string{"PID", "PPID", "STAT", "COMMAND"} {
+		if _, ok := columns[title]; !ok {
+			return []containerProcess{}, fmt.Errorf("unable to list container processes: missing %s column", title)
+		}
+	}
+
+	processes := []containerProcess{}
+	for _, row := range rows {
+		if len(row) != len(titles) {
+			continue
+		}
+
+		pid, err := strconv.Atoi(row[columns["PID"]])
+		if err != nil {
+			return []containerProcess{}, fmt.Errorf("unable to parse process pid: %w", err)
+		}
+
+		ppid, err := strconv.Atoi(row[columns["PPID"]])
+		if err != nil {
+			return []containerProcess{}, fmt.Errorf("unable to parse process ppid: %w", err)
+		}
+
+		processes = append(processes, containerProcess{
+			Command: row[columns["COMMAND"]],
+			PID:     pid,
+			PPID:    ppid,
+			State:   row[columns["STAT"]],
+		})
+	}
+
+	return processes, nil
+}
@@ -0,0 +1,97 @@
+package appjson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseContainerProcesses(t *testing.T) {
+	titles := []string{"PID", "PPID", "STAT", "COMMAND"}
+	rows := [][]string{
+		{"1234", "1200", "Ss", "/usr/bin/supervisord -n"},
+		{"1240", "1234", "S", "python worker.py --queue default"},
+		{"1241", "1234", "Z", "[python] <defunct>"},
+	}
+
+	processes, err := parseContainerProcesses(titles, rows)
+	if err != nil {
+		t.Fatalf("parseContainerProcesses() error = %v", err)
+	}
+
+	if len(processes) != 3 {
+		t.Fatalf("parseContainerProcesses() returned %d processes, want 3", len(processes))
+	}
+
+	if processes[2].PPID != 1234 || processes[2].State != "Z" {
+		t.Errorf("parseContainerProcesses() = %+v, want ppid 1234 and state Z", processes[2])
+	}
+
+	if _, err := parseContainerProcesses([]string{"UID", "COMMAND"}, rows); err == nil {
+		t.Errorf("parseContainerProcesses() expected error for missing PID column")
+	}
+}
+
+func TestProcessMatcher_compile(t *testing.T) {
+	process := containerProcess{Command: "/usr/bin/supervisord -n"}
+	tests := []struct {
+		name    string
+		matcher ProcessMatcher
+		want    bool
+	}{
+		{
+			name:    "when the name matches the binary",
+			matcher: ProcessMatcher{Name: "supervisord"},
+			want:    true,
+		},
+		{
+			name:    "when the name matches an argument",
+			matcher: ProcessMatcher{Name: "-n"},
+			want:    false,
+		},
+		{
+			name:    "when the pattern matches the command line",
+			matcher: ProcessMatcher{Pattern: "supervisord\\s+-n"},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := tt.matcher.compile()
+			if err != nil {
+				t.Fatalf("ProcessMatcher.compile() error = %v", err)
+			}
+
+			if got := matches(process); got != tt.want {
+				t.Errorf("ProcessMatcher.compile() matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMatcher_GetMin(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{name: "when min is not set", data: `{"name":"worker"}`, want: 1},
+		{name: "when min is set", data: `{"name":"worker","min":4}`, want: 4},
+		{name: "when min is explicitly zero", data: `{"name":"worker","min":0,"max":2}`, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var matcher ProcessMatcher
+			if err := json.Unmarshal([]byte(tt.data), &matcher); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+
+			if err := matcher.Validate(); err != nil {
+				t.Errorf("ProcessMatcher.Validate() error = %v", err)
+			}
+
+			if got := matcher.GetMin(); got != tt.want {
+				t.Errorf("ProcessMatcher.GetMin() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
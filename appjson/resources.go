@@ -0,0 +1,183 @@
+package appjson
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+type ResourceLimits struct {
+	MaxCPUPercent    float64 `json:"maxCpuPercent,omitempty"`
+	MaxMemoryPercent float64 `json:"maxMemoryPercent,omitempty"`
+	MaxPids          int     `json:"maxPids,omitempty"`
+	SampleSeconds    int     `json:"sampleSeconds,omitempty"`
+}
+
+func (r ResourceLimits) GetSampleSeconds() int {
+	if r.SampleSeconds <= 0 {
+		return 5
+	}
+
+	return r.SampleSeconds
+}
+
+func (r ResourceLimits) Validate() error {
+	if r.MaxCPUPercent < 0 || r.MaxMemoryPercent < 0 || r.MaxPids < 0 || r.SampleSeconds < 0 {
+		return errors.New("resource limits must not be negative")
+	}
+
+	if r.MaxCPUPercent == 0 && r.MaxMemoryPercent == 0 && r.MaxPids == 0 {
+		return errors.New("at least one of 'maxCpuPercent', 'maxMemoryPercent', or 'maxPids' must be set")
+	}
+
+	if r.MaxMemoryPercent > 100 {
+		return errors.New("'maxMemoryPercent' must not be greater than 100")
+	}
+
+	return nil
+}
+
+type resourcesChecker struct{}
+
+func (resourcesChecker) Configured(h Healthcheck) bool {
+	return h.Resources != nil
+}
+
+func (resourcesChecker) Validate(h Healthcheck) error {
+	if h.Resources == nil {
+		return fmt.Errorf("healthcheck name='%s' requires a 'resources' value for the 'resources' strategy", h.GetName())
+	}
+
+	if err := h.Resources.Validate(); err != nil {
+		return fmt.Errorf("healthcheck name='%s' has an invalid 'resources' value: %w", h.GetName(), err)
+	}
+
+	return nil
+}
+
+func (resourcesChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("attempts=%d maxCpuPercent=%.2f maxMemoryPercent=%.2f maxPids=%d sampleSeconds=%d timeout=%d type='resources' wait=%d", h.GetAttempts(), h.Resources.MaxCPUPercent, h.Resources.MaxMemoryPercent, h.Resources.MaxPids, h.Resources.GetSampleSeconds(), h.GetTimeout(), h.GetWait())
+}
+
+func (resourcesChecker) attemptTimeout(h Healthcheck) time.Duration {
+	timeout := time.Duration(h.GetTimeout()) * time.Second
+	// an invalid healthcheck without a resources value is only rejected
+	// once it runs, after its worst-case duration is estimated
+	if h.Resources != nil && h.Resources.MaxCPUPercent > 0 {
+		// cpu usage is sampled twice, each stats call being limited by the timeout
+		return 2*timeout + time.Duration(h.Resources.GetSampleSeconds())*time.Second
+	}
+
+	return timeout
+}
+
+func (resourcesChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return withDockerClient(hctx, func(cli *client.Client) ([]byte, error) {
+		return h.resourcesCheck(ctx, cli, container)
+	})
+}
+
+func (h Healthcheck) resourcesCheck(ctx context.Context, cli *client.Client, container container_types.InspectResponse) ([]byte, error) {
+	limits := *h.Resources
+	first, err := h.containerStats(ctx, cli, container)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	measurements := []string{}
+	violations := []string{}
+
+	if limits.MaxMemoryPercent > 0 {
+		used, limit, percent := memoryUsage(first.MemoryStats)
+		measurements = append(measurements, fmt.Sprintf("memory=%d/%d (%.2f%%)", used, limit, percent))
+		if percent > limits.MaxMemoryPercent {
+			violations = append(violations, fmt.Sprintf("memory usage %.2f%% exceeds %.2f%%", percent, limits.MaxMemoryPercent))
+		}
+	}
+
+	if limits.MaxPids > 0 {
+		measurements = append(measurements, fmt.Sprintf("pids=%d", first.PidsStats.Current))
+		if first.PidsStats.Current > uint64(limits.MaxPids) {
+			violations = append(violations, fmt.Sprintf("pid count %d exceeds %d", first.PidsStats.Current, limits.MaxPids))
+		}
+	}
+
+	if limits.MaxCPUPercent > 0 {
+		if err := SleepContext(ctx, time.Duration(limits.GetSampleSeconds())*time.Second); err != nil {
+			return []byte(strings.Join(measurements, " ")), err
+		}
+		second, err := h.containerStats(ctx, cli, container)
+		if err != nil {
+			return []byte(strings.Join(measurements, " ")), err
+		}
+
+		percent := cpuPercent(first.CPUStats, second.CPUStats)
+		measurements = append(measurements, fmt.Sprintf("cpu=%.2f%% window=%ds", percent, limits.GetSampleSeconds()))
+		if percent > limits.MaxCPUPercent {
+			violations = append(violations, fmt.Sprintf("cpu usage %.2f%% exceeds %.2f%%", percent, limits.MaxCPUPercent))
+		}
+	}
+
+	b := []byte(strings.Join(measurements, " "))
+	if len(violations) > 0 {
+		return b, fmt.Errorf("container resource usage over threshold: %s", strings.Join(violations, ", "))
+	}
+
+	return b, nil
+}
+
+func (h Healthcheck) containerStats(ctx context.Context, cli *client.Client, container container_types.InspectResponse) (container_types.StatsResponse, error) {
+	var stats container_types.StatsResponse
+	response, err := cli.ContainerStats(ctx, container.ID, client.ContainerStatsOptions{})
+	if err != nil {
+		return stats, fmt.Errorf("unable to fetch container stats: %w", err)
+	}
+	defer response.Body.Close()
+
+	if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+		return stats, fmt.Errorf("unable to decode container stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// memoryUsage returns the used bytes, limit bytes, and usage percentage the
+// same way the docker cli does, excluding the inactive page cache
+func memoryUsage(stats container_types.MemoryStats) (uint64, uint64, float64) {
+	used := stats.Usage
+	for _, key := range []string{"total_inactive_file", "inactive_file"} {
+		if value, ok := stats.Stats[key]; ok && value < used {
+			used -= value
+			break
+		}
+	}
+
+	if stats.Limit == 0 {
+		return used, stats.Limit, 0
+	}
+
+	return used, stats.Limit, float64(used) / float64(stats.Limit) * 100.0
+}
+
+// cpuPercent returns the cpu usage between two samples the same way the
+// docker cli does, where 100% is equal to a single fully used cpu
+func cpuPercent(previous container_types.CPUStats, current container_types.CPUStats) float64 {
+	if current.CPUUsage.TotalUsage < previous.CPUUsage.TotalUsage || current.SystemUsage <= previous.SystemUsage {
+		return 0
+	}
+
+	cpuDelta := float64(current.CPUUsage.TotalUsage - previous.CPUUsage.TotalUsage)
+	systemDelta := float64(current.SystemUsage - previous.SystemUsage)
+	onlineCPUs := float64(current.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(current.CPUUsage.PercpuUsage))
+	}
+
+	return cpuDelta / systemDelta * onlineCPUs * 100.0
+}
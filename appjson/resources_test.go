@@ -0,0 +1,58 @@
+package appjson
+
+import (
+	"testing"
+
+	container_types "github.com/moby/moby/api/types/container"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	tests := []struct {
+		name        string
+		stats       container_types.MemoryStats
+		wantUsed    uint64
+		wantPercent float64
+	}{
+		{
+			name:        "when there is no limit",
+			stats:       container_types.MemoryStats{Usage: 100},
+			wantUsed:    100,
+			wantPercent: 0,
+		},
+		{
+			name:        "when inactive file cache is reported",
+			stats:       container_types.MemoryStats{Usage: 300, Limit: 1000, Stats: map[string]uint64{"inactive_file": 100}},
+			wantUsed:    200,
+			wantPercent: 20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used, _, percent := memoryUsage(tt.stats)
+			if used != tt.wantUsed || percent != tt.wantPercent {
+				t.Errorf("memoryUsage() = %d, %.2f, want %d, %.2f", used, percent, tt.wantUsed, tt.wantPercent)
+			}
+		})
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	previous := container_types.CPUStats{
+		CPUUsage:    container_types.CPUUsage{TotalUsage: 1000},
+		SystemUsage: 10000,
+		OnlineCPUs:  2,
+	}
+	current := container_types.CPUStats{
+		CPUUsage:    container_types.CPUUsage{TotalUsage: 2000},
+		SystemUsage: 20000,
+		OnlineCPUs:  2,
+	}
+
+	if percent := cpuPercent(previous, current); percent != 20 {
+		t.Errorf("cpuPercent() = %.2f, want 20", percent)
+	}
+
+	if percent := cpuPercent(current, previous); percent != 0 {
+		t.Errorf("cpuPercent() = %.2f, want 0", percent)
+	}
+}
@@ -0,0 +1,201 @@
+package appjson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	container_types "github.com/moby/moby/api/types/container"
+)
+
+// Attempt records the outcome of a single attempt of a healthcheck
+type Attempt struct {
+	// Number is the attempt number, starting from 1
+	Number int
+
+	// Attempts is the maximum number of attempts of the healthcheck
+	Attempts int
+
+	// Start is when the attempt started
+	Start time.Time
+
+	// Duration is how long the attempt ran for
+	Duration time.Duration
+
+	// Err is the error the attempt failed with, or nil when it passed
+	Err error
+}
+
+// unrecoverableError marks an attempt error that no later attempt can fix
+type unrecoverableError struct {
+	Err error
+}
+
+func (e unrecoverableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e unrecoverableError) Unwrap() error {
+	return e.Err
+}
+
+// Unrecoverable wraps an attempt error so the healthcheck stops without further attempts
+func Unrecoverable(err error) error {
+	return unrecoverableError{Err: err}
+}
+
+func (h Healthcheck) GetSuccessThreshold() int {
+	if h.SuccessThreshold <= 0 {
+		return 1
+	}
+
+	return h.SuccessThreshold
+}
+
+// GetFailureThreshold returns the number of consecutive failed attempts
+// after which the check fails, which defaults to the configured attempts
+func (h Healthcheck) GetFailureThreshold() int {
+	if h.FailureThreshold > 0 {
+		return h.FailureThreshold
+	}
+
+	if h.Attempts > 0 {
+		return h.Attempts
+	}
+
+	return 3
+}
+
+func (h Healthcheck) validateThresholds() error {
+	if h.SuccessThreshold < 0 {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a negative 'successThreshold' value", h.GetName())
+	}
+
+	if h.FailureThreshold < 0 {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a negative 'failureThreshold' value", h.GetName())
+	}
+
+	if h.GetSuccessThreshold() > h.GetAttempts() {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a 'successThreshold' value greater than its %d attempts", h.GetName(), h.GetAttempts())
+	}
+
+	if h.GetFailureThreshold() > h.GetAttempts() {
+		return fmt.Errorf("healthcheck name='%s' cannot contain a 'failureThreshold' value greater than its %d attempts", h.GetName(), h.GetAttempts())
+	}
+
+	return nil
+}
+
+// attemptTimeout returns how long a single attempt of the strategy may run for
+func (h Healthcheck) attemptTimeout(checker Checker) time.Duration {
+	if timeouter, ok := checker.(attemptTimeouter); ok {
+		return timeouter.attemptTimeout(h)
+	}
+
+	return time.Duration(h.GetTimeout()) * time.Second
+}
+
+// runAttempts runs attempts of the strategy until one succeeds
+// successThreshold times in a row, fails failureThreshold times in a row,
+// returns a warning or an unrecoverable error, or the attempts run out. Each attempt is
+// limited to the attempt timeout. Failed attempts are followed by the
+// backoff wait, while successful attempts short of the threshold are
+// followed by the plain wait. The output of the last attempt is returned
+// along with the error of every failed attempt when the check does not pass.
+func (h Healthcheck) runAttempts(ctx context.Context, checker Checker, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, []error) {
+	if delayer, ok := checker.(startDelayer); ok && container.State != nil {
+		startedAt, err := time.Parse(time.RFC3339Nano, container.State.StartedAt)
+		if err != nil {
+			return []byte{}, []error{err}
+		}
+
+		if err := SleepContext(ctx, delayer.startDelay(h, time.Since(startedAt))); err != nil {
+			// the check was cancelled before its first attempt
+			return []byte{}, []error{}
+		}
+	}
+
+	if ctx.Err() != nil {
+		return []byte{}, []error{}
+	}
+
+	var b []byte
+	errs := []error{}
+	successes, failures := 0, 0
+	for n := 1; n <= h.GetAttempts(); n++ {
+		var err error
+		b, err = h.runAttempt(ctx, checker, container, hctx, n)
+		if ctx.Err() != nil {
+			// the check was cancelled rather than the attempt failing on its own
+			return b, errs
+		}
+
+		if IsWarning(err) {
+			// a warning is a final result that no later attempt changes
+			return b, []error{err}
+		}
+
+		if err == nil {
+			successes, failures = successes+1, 0
+			if successes >= h.GetSuccessThreshold() {
+				return b, nil
+			}
+		} else {
+			successes, failures = 0, failures+1
+
+			var unrecoverableErr unrecoverableError
+			if errors.As(err, &unrecoverableErr) {
+				return b, append(errs, unrecoverableErr.Err)
+			}
+
+			errs = append(errs, err)
+			if failures >= h.GetFailureThreshold() {
+				return b, errs
+			}
+		}
+
+		if n == h.GetAttempts() {
+			break
+		}
+
+		delay := time.Duration(h.GetWait()) * time.Second
+		if failures > 0 {
+			delay = h.retryDelay(uint(failures-1)) + h.retryJitter()
+		}
+		if err := SleepContext(ctx, delay); err != nil {
+			return b, errs
+		}
+	}
+
+	if successes > 0 {
+		errs = append(errs, fmt.Errorf("only %d of %d required consecutive attempts succeeded", successes, h.GetSuccessThreshold()))
+	}
+
+	return b, errs
+}
+
+// runAttempt runs a single attempt under the attempt timeout, reporting its record to the context
+func (h Healthcheck) runAttempt(ctx context.Context, checker Checker, container container_types.InspectResponse, hctx HealthcheckContext, n int) ([]byte, error) {
+	timeout := h.attemptTimeout(checker)
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	b, err := checker.Execute(attemptCtx, h, container, hctx)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("attempt timed out after %s: %w", timeout, err)
+	}
+
+	if hctx.OnAttempt != nil && ctx.Err() == nil {
+		hctx.OnAttempt(Attempt{
+			Number:   n,
+			Attempts: h.GetAttempts(),
+			Start:    start,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+
+	return b, err
+}
@@ -0,0 +1,149 @@
+package appjson
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	container_types "github.com/moby/moby/api/types/container"
+)
+
+// scriptedChecker returns the next scripted result on every attempt
+type scriptedChecker struct {
+	staticChecker
+	results  []error
+	attempts *int
+}
+
+func (c scriptedChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	err := c.results[*c.attempts]
+	*c.attempts++
+	return []byte{}, err
+}
+
+func TestHealthcheck_runAttempts(t *testing.T) {
+	failure := errors.New("attempt failed")
+	tests := []struct {
+		name         string
+		healthcheck  Healthcheck
+		results      []error
+		wantAttempts int
+		wantErrs     int
+	}{
+		{
+			name:         "when the success threshold is reached",
+			healthcheck:  Healthcheck{Attempts: 3, SuccessThreshold: 2, Wait: 1},
+			results:      []error{failure, nil, nil},
+			wantAttempts: 3,
+			wantErrs:     0,
+		},
+		{
+			name:         "when the attempts run out before the success threshold",
+			healthcheck:  Healthcheck{Attempts: 2, SuccessThreshold: 2, Wait: 1},
+			results:      []error{failure, nil},
+			wantAttempts: 2,
+			wantErrs:     2,
+		},
+		{
+			name:         "when the failure threshold is reached",
+			healthcheck:  Healthcheck{Attempts: 5, FailureThreshold: 2, Wait: 1},
+			results:      []error{failure, failure, nil},
+			wantAttempts: 2,
+			wantErrs:     2,
+		},
+		{
+			name:         "when an attempt is unrecoverable",
+			healthcheck:  Healthcheck{Attempts: 3, Wait: 1},
+			results:      []error{Unrecoverable(failure), nil},
+			wantAttempts: 1,
+			wantErrs:     1,
+		},
+		{
+			name:         "when an attempt warns",
+			healthcheck:  Healthcheck{Attempts: 3, Wait: 1},
+			results:      []error{failure, WarningError{Err: failure}, failure},
+			wantAttempts: 2,
+			wantErrs:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			checker := scriptedChecker{results: tt.results, attempts: &attempts}
+			_, errs := tt.healthcheck.runAttempts(context.Background(), checker, container_types.InspectResponse{}, HealthcheckContext{})
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("Healthcheck.runAttempts() attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(errs) != tt.wantErrs {
+				t.Errorf("Healthcheck.runAttempts() errors = %v, want %d errors", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestHealthcheck_GetAttempts(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthcheck Healthcheck
+		want        int
+	}{
+		{name: "when using the defaults", healthcheck: Healthcheck{}, want: 3},
+		{name: "when attempts is set", healthcheck: Healthcheck{Attempts: 5, SuccessThreshold: 2}, want: 5},
+		{name: "when the failure threshold is set", healthcheck: Healthcheck{FailureThreshold: 4}, want: 4},
+		{name: "when both thresholds are set", healthcheck: Healthcheck{FailureThreshold: 4, SuccessThreshold: 3}, want: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.healthcheck.GetAttempts(); got != tt.want {
+				t.Errorf("Healthcheck.GetAttempts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthcheck_runAttemptsRecords(t *testing.T) {
+	failure := errors.New("attempt failed")
+	attempts := 0
+	checker := scriptedChecker{results: []error{failure, nil}, attempts: &attempts}
+
+	records := []Attempt{}
+	hctx := HealthcheckContext{OnAttempt: func(a Attempt) { records = append(records, a) }}
+	h := Healthcheck{Attempts: 3, Wait: 1}
+	if _, errs := h.runAttempts(context.Background(), checker, container_types.InspectResponse{}, hctx); len(errs) > 0 {
+		t.Fatalf("Healthcheck.runAttempts() errors = %v, want none", errs)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Healthcheck.runAttempts() recorded %d attempts, want 2", len(records))
+	}
+	if records[0].Number != 1 || records[0].Attempts != 3 || !errors.Is(records[0].Err, failure) {
+		t.Errorf("first attempt = %+v, want attempt 1/3 failing", records[0])
+	}
+	if records[1].Number != 2 || records[1].Err != nil || records[1].Start.Before(records[0].Start.Add(time.Second)) {
+		t.Errorf("second attempt = %+v, want attempt 2/3 passing after the wait", records[1])
+	}
+}
+
+func TestHealthcheck_runAttemptsTimeout(t *testing.T) {
+	h := Healthcheck{HostCommand: []string{"sleep", "10"}, Attempts: 1, Timeout: 1}
+	checker, _ := GetChecker(HostCommandCheck)
+
+	_, errs := h.runAttempts(context.Background(), checker, container_types.InspectResponse{}, HealthcheckContext{IPAddress: "10.0.0.2"})
+	if len(errs) != 1 || errs[0].Error() != "attempt timed out after 1s: unable to execute host command: context deadline exceeded" {
+		t.Errorf("Healthcheck.runAttempts() errors = %v, want a timed out attempt", errs)
+	}
+}
+
+func TestHealthcheck_ExecuteTimedOutDuringStartDelay(t *testing.T) {
+	h := Healthcheck{Uptime: 10}
+	container := container_types.InspectResponse{State: &container_types.State{Running: true, StartedAt: time.Now().Format(time.RFC3339Nano)}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, errs := h.Execute(ctx, container, HealthcheckContext{})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrTimedOut) {
+		t.Errorf("Healthcheck.Execute() errors = %v, want only ErrTimedOut", errs)
+	}
+}
@@ -0,0 +1,122 @@
+package appjson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+type ZombieThreshold struct {
+	Action    string `json:"action,omitempty"`
+	Threshold int    `json:"threshold,omitempty"`
+}
+
+func (z ZombieThreshold) GetAction() string {
+	if z.Action == "" {
+		return "fail"
+	}
+
+	return z.Action
+}
+
+func (z ZombieThreshold) Validate() error {
+	validActions := map[string]bool{
+		"fail": true,
+		"warn": true,
+	}
+	if !validActions[z.GetAction()] {
+		return errors.New("invalid action specified, must be either fail or warn")
+	}
+
+	if z.Threshold < 0 {
+		return errors.New("'threshold' must not be negative")
+	}
+
+	return nil
+}
+
+type zombiesChecker struct{}
+
+func (zombiesChecker) Configured(h Healthcheck) bool {
+	return h.Zombies != nil
+}
+
+func (zombiesChecker) Validate(h Healthcheck) error {
+	if h.Zombies == nil {
+		return fmt.Errorf("healthcheck name='%s' requires a 'zombies' value for the 'zombies' strategy", h.GetName())
+	}
+
+	if err := h.Zombies.Validate(); err != nil {
+		return fmt.Errorf("healthcheck name='%s' has an invalid 'zombies' value: %w", h.GetName(), err)
+	}
+
+	return nil
+}
+
+func (zombiesChecker) Describe(h Healthcheck, container container_types.InspectResponse) string {
+	return fmt.Sprintf("action='%s' attempts=%d threshold=%d timeout=%d type='zombies' wait=%d", h.Zombies.GetAction(), h.GetAttempts(), h.Zombies.Threshold, h.GetTimeout(), h.GetWait())
+}
+
+func (zombiesChecker) Execute(ctx context.Context, h Healthcheck, container container_types.InspectResponse, hctx HealthcheckContext) ([]byte, error) {
+	return withDockerClient(hctx, func(cli *client.Client) ([]byte, error) {
+		return h.zombiesCheck(ctx, cli, container)
+	})
+}
+
+func (h Healthcheck) zombiesCheck(ctx context.Context, cli *client.Client, container container_types.InspectResponse) ([]byte, error) {
+	processes, err := containerProcesses(ctx, cli, container)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	count, lines := zombieParents(processes)
+	b := []byte(strings.Join(lines, "\n"))
+	if count <= h.Zombies.Threshold {
+		return b, nil
+	}
+
+	err = fmt.Errorf("container has %d zombie processes, more than the allowed %d", count, h.Zombies.Threshold)
+	if h.Zombies.GetAction() == "warn" {
+		return b, WarningError{Err: err}
+	}
+
+	return b, err
+}
+
+// zombieParents returns the number of zombie processes along with a
+// summary line for every parent process that has not reaped its children
+func zombieParents(processes []containerProcess) (int, []string) {
+	commands := map[int]string{}
+	for _, process := range processes {
+		commands[process.PID] = process.Command
+	}
+
+	count := 0
+	parents := map[int]int{}
+	for _, process := range processes {
+		if !strings.HasPrefix(process.State, "Z") {
+			continue
+		}
+
+		count++
+		parents[process.PPID]++
+	}
+
+	ppids := []int{}
+	for ppid := range parents {
+		ppids = append(ppids, ppid)
+	}
+	sort.Ints(ppids)
+
+	lines := []string{}
+	for _, ppid := range ppids {
+		lines = append(lines, fmt.Sprintf("ppid=%d zombies=%d command='%s'", ppid, parents[ppid], commands[ppid]))
+	}
+
+	return count, lines
+}
@@ -0,0 +1,22 @@
+package appjson
+
+import "testing"
+
+func TestZombieParents(t *testing.T) {
+	processes := []containerProcess{
+		{PID: 10, PPID: 1, State: "Ss", Command: "node server.js"},
+		{PID: 11, PPID: 10, State: "Z", Command: "[sh] <defunct>"},
+		{PID: 12, PPID: 10, State: "Z+", Command: "[sh] <defunct>"},
+		{PID: 13, PPID: 10, State: "S", Command: "sleep 10"},
+	}
+
+	count, lines := zombieParents(processes)
+	if count != 2 {
+		t.Errorf("zombieParents() count = %d, want 2", count)
+	}
+
+	want := "ppid=10 zombies=2 command='node server.js'"
+	if len(lines) != 1 || lines[0] != want {
+		t.Errorf("zombieParents() lines = %v, want [%s]", lines, want)
+	}
+}
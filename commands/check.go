@@ -3,10 +3,13 @@ package commands
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 	"unicode"
 
@@ -25,10 +28,14 @@ type CheckCommand struct {
 	appJSONFile string
 	headers     []string
 	checkType   string
+	concurrency int
+	deadline    int
+	failFast    bool
 	ipAddress   string
 	networkName string
 	port        int
 	processType string
+	showOutput  bool
 }
 
 func (c *CheckCommand) Name() string {
@@ -72,12 +79,16 @@ func (c *CheckCommand) ParsedArguments(args []string) (map[string]command.Argume
 func (c *CheckCommand) FlagSet() *flag.FlagSet {
 	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
 	f.IntVar(&c.port, "port", 5000, "container port to check")
+	f.IntVar(&c.concurrency, "concurrency", 0, "maximum number of checks to run at once, overriding the process type concurrency")
+	f.IntVar(&c.deadline, "deadline", 0, "seconds after which all remaining checks are cancelled, overriding the process type deadline")
+	f.BoolVar(&c.failFast, "fail-fast", false, "cancel the remaining checks as soon as any non-warn check fails")
 	f.StringSliceVar(&c.headers, "header", []string{}, "one or more headers in 'curl -H' format to specify for path requests")
 	f.StringVar(&c.appJSONFile, "app-json", "app.json", "full path to app.json file")
 	f.StringVar(&c.checkType, "type", "startup", "check to interpret")
 	f.StringVar(&c.ipAddress, "ip-address", "", "an ip address to use for http 'path' checks")
 	f.StringVar(&c.networkName, "network", "bridge", "container network to use for http 'path' checks")
 	f.StringVar(&c.processType, "process-type", "web", "process type to check")
+	f.BoolVar(&c.showOutput, "show-output", false, "show healthcheck output for successful checks")
 	return f
 }
 
@@ -86,11 +97,15 @@ func (c *CheckCommand) AutocompleteFlags() complete.Flags {
 		c.Meta.AutocompleteFlags(command.FlagSetClient),
 		complete.Flags{
 			"--app-json":     complete.PredictAnything,
+			"--concurrency":  complete.PredictAnything,
+			"--deadline":     complete.PredictAnything,
+			"--fail-fast":    complete.PredictNothing,
 			"--header":       complete.PredictAnything,
 			"--ip-address":   complete.PredictAnything,
 			"--network":      complete.PredictAnything,
 			"--port":         complete.PredictAnything,
 			"--process-type": complete.PredictAnything,
+			"--show-output":  complete.PredictNothing,
 			"--type":         complete.PredictSet("liveness", "readiness", "startup"),
 		},
 	)
@@ -118,15 +133,25 @@ func (c *CheckCommand) Run(args []string) int {
 		return 1
 	}
 
-	b, err := os.ReadFile(c.appJSONFile)
+	// cancel in-flight checks on interrupt rather than waiting for them to finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	appJSON, err := readAppJSON(c.appJSONFile)
 	if err != nil {
 		logger.Error(err.Error())
 		return 1
 	}
 
-	var appJSON appjson.AppJSON
-	if err := json.Unmarshal(b, &appJSON); err != nil {
-		logger.Error(err.Error())
+	settings := appJSON.GetSettings(c.processType)
+	if flags.Changed("concurrency") {
+		settings.Concurrency = c.concurrency
+	}
+	if flags.Changed("deadline") {
+		settings.Deadline = c.deadline
+	}
+	if err := settings.Validate(); err != nil {
+		logger.Error(fmt.Sprintf("Invalid healthcheck settings for process type '%s': %s", c.processType, err.Error()))
 		return 1
 	}
 
@@ -139,8 +164,9 @@ func (c *CheckCommand) Run(args []string) int {
 		logger.Error(err.Error())
 		return 1
 	}
+	defer cli.Close()
 
-	inspect, err := cli.ContainerInspect(context.Background(), containerIDorName, client.ContainerInspectOptions{})
+	inspect, err := cli.ContainerInspect(ctx, containerIDorName, client.ContainerInspectOptions{})
 	if err != nil {
 		logger.Error(err.Error())
 		return 1
@@ -152,48 +178,119 @@ func (c *CheckCommand) Run(args []string) int {
 		return 1
 	}
 
-	var healthchecks []appjson.Healthcheck
+	healthchecks := collectHealthchecks(appJSON, c.processType, c.checkType, c.port, logger)
 
-	// collect the healthchecks
-	for processType, data := range appJSON.Healthchecks {
-		if processType != c.processType {
-			continue
-		}
-
-		for _, healthcheck := range data {
-			if healthcheck.Type == "" {
-				logger.Error(fmt.Sprintf("Missing type field on healthcheck name='%s'", healthcheck.GetName()))
-			}
-
-			if healthcheck.Type != c.checkType {
-				continue
-			}
-
-			if healthcheck.Port == 0 {
-				healthcheck.Port = c.port
-			}
+	if err := appjson.ValidateDependencies(healthchecks); err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
 
-			healthchecks = append(healthchecks, healthcheck)
-		}
+	header := fmt.Sprintf("Executing %d healthchecks (worst-case duration: %s", len(healthchecks), appjson.MaxDuration(healthchecks, settings.Concurrency))
+	if settings.Concurrency > 0 {
+		header += fmt.Sprintf(", concurrency: %d", settings.Concurrency)
 	}
+	if settings.Deadline > 0 {
+		header += fmt.Sprintf(", deadline: %s", time.Duration(settings.Deadline)*time.Second)
 
-	if len(healthchecks) == 0 {
-		healthchecks = append(healthchecks, appjson.Healthcheck{
-			Name:   "autogenerated",
-			Type:   c.checkType,
-			Uptime: 10,
-		})
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(settings.Deadline)*time.Second)
+		defer cancel()
+	}
+	logger.LogHeader2(header + ")")
+
+	cancelRemaining := func(error) {}
+	if c.failFast {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		cancelRemaining = cancel
 	}
 
-	logger.LogHeader2(fmt.Sprintf("Executing %d healthchecks", len(healthchecks)))
 	var wg sync.WaitGroup
 	responseChan := make(chan HealthcheckResponse)
-	for _, healthcheck := range healthchecks {
+
+	// each healthcheck records whether it passed before closing its finished
+	// channel, so dependents only start once all of their dependencies pass
+	indexes := map[string]int{}
+	finished := make([]chan struct{}, len(healthchecks))
+	passed := make([]bool, len(healthchecks))
+	for i, healthcheck := range healthchecks {
+		indexes[healthcheck.GetName()] = i
+		finished[i] = make(chan struct{})
+	}
+
+	slots := newCheckSlots(settings.Concurrency)
+	for i, healthcheck := range healthchecks {
+		// healthchecks without dependencies queue up front so that
+		// free slots are handed out in declaration order
+		var granted <-chan struct{}
+		if len(healthcheck.DependsOn) == 0 {
+			granted = slots.wait(i)
+		}
+
 		wg.Add(1)
-		go func(h appjson.Healthcheck) {
+		go func(i int, h appjson.Healthcheck, granted <-chan struct{}) {
 			defer wg.Done()
-			responseChan <- c.processHealthcheck(h, container, logger)
-		}(healthcheck)
+			defer close(finished[i])
+
+			for _, dependency := range h.DependsOn {
+				select {
+				case <-finished[indexes[dependency]]:
+				case <-ctx.Done():
+					responseChan <- HealthcheckResponse{
+						HealthcheckName: h.GetName(),
+						Errors:          []error{appjson.ContextError(ctx)},
+						Warn:            h.Warn,
+					}
+					return
+				}
+
+				// with fail-fast, a failed dependency cancels its dependents
+				// rather than skipping them, even before its own result is
+				// processed, so they are never counted as failures
+				if !passed[indexes[dependency]] && !healthchecks[indexes[dependency]].Warn {
+					cancelRemaining(fmt.Errorf("%w: name='%s' failed", errFailFast, dependency))
+				}
+
+				if ctx.Err() != nil {
+					responseChan <- HealthcheckResponse{
+						HealthcheckName: h.GetName(),
+						Errors:          []error{appjson.ContextError(ctx)},
+						Warn:            h.Warn,
+					}
+					return
+				}
+
+				if !passed[indexes[dependency]] {
+					responseChan <- HealthcheckResponse{
+						HealthcheckName: h.GetName(),
+						Errors:          []error{skippedError{Dependency: dependency}},
+						Warn:            h.Warn,
+					}
+					return
+				}
+			}
+
+			if granted == nil {
+				granted = slots.wait(i)
+			}
+			select {
+			case <-granted:
+			case <-ctx.Done():
+				slots.cancel(i)
+				responseChan <- HealthcheckResponse{
+					HealthcheckName: h.GetName(),
+					Errors:          []error{appjson.ContextError(ctx)},
+					Warn:            h.Warn,
+				}
+				return
+			}
+
+			resp := c.processHealthcheck(ctx, cli, h, container, logger)
+			slots.release()
+			passed[i] = resp.Passed()
+			responseChan <- resp
+		}(i, healthcheck, granted)
 	}
 
 	go func() {
@@ -203,11 +300,32 @@ func (c *CheckCommand) Run(args []string) int {
 
 	errorCount := 0
 	for resp := range responseChan {
-		if !resp.Warn {
+		failFastCancelled := resp.Cancelled() && errors.Is(context.Cause(ctx), errFailFast)
+		if !resp.Warn && !resp.Warned() && !failFastCancelled {
 			errorCount += len(resp.Errors)
 		}
 
-		if len(resp.Errors) > 0 {
+		if !resp.Warn && !resp.Warned() && !resp.Cancelled() && !resp.Skipped() && len(resp.Errors) > 0 {
+			cancelRemaining(fmt.Errorf("%w: name='%s' failed", errFailFast, resp.HealthcheckName))
+		}
+
+		for _, p := range resp.Perfdata {
+			logger.Info(fmt.Sprintf("Perfdata name='%s' %s", resp.HealthcheckName, p.String()))
+		}
+
+		if resp.Warned() {
+			err := resp.Errors[len(resp.Errors)-1]
+			logger.Warn(fmt.Sprintf("Warning in name='%s': %s", resp.HealthcheckName, err.Error()))
+		} else if resp.Skipped() {
+			err := resp.Errors[len(resp.Errors)-1]
+			logger.Error(fmt.Sprintf("Skipped name='%s': %s", resp.HealthcheckName, err.Error()))
+		} else if failFastCancelled {
+			logger.Error(fmt.Sprintf("Cancelled name='%s': %s", resp.HealthcheckName, context.Cause(ctx).Error()))
+		} else if resp.Cancelled() {
+			logger.Error(fmt.Sprintf("Cancelled name='%s'", resp.HealthcheckName))
+		} else if resp.TimedOut() {
+			logger.Error(fmt.Sprintf("Timed out name='%s'", resp.HealthcheckName))
+		} else if len(resp.Errors) > 0 {
 			err := resp.Errors[len(resp.Errors)-1]
 			logger.Error(fmt.Sprintf("Failure in name='%s': %s", resp.HealthcheckName, err.Error()))
 		} else {
@@ -218,13 +336,202 @@ func (c *CheckCommand) Run(args []string) int {
 	return errorCount
 }
 
+// readAppJSON parses the app.json file at the path
+func readAppJSON(path string) (appjson.AppJSON, error) {
+	var appJSON appjson.AppJSON
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return appJSON, err
+	}
+
+	if err := json.Unmarshal(b, &appJSON); err != nil {
+		return appJSON, err
+	}
+
+	return appJSON, nil
+}
+
+// collectHealthchecks returns the healthchecks of the process type and
+// healthcheck type, falling back to an autogenerated uptime check
+func collectHealthchecks(appJSON appjson.AppJSON, processType string, checkType string, port int, logger *command.ZerologUi) []appjson.Healthcheck {
+	var healthchecks []appjson.Healthcheck
+	for _, healthcheck := range appJSON.Healthchecks[processType] {
+		if healthcheck.Type == "" {
+			logger.Error(fmt.Sprintf("Missing type field on healthcheck name='%s'", healthcheck.GetName()))
+		}
+
+		if healthcheck.Type != checkType {
+			continue
+		}
+
+		if healthcheck.Port == 0 {
+			healthcheck.Port = port
+		}
+
+		healthchecks = append(healthchecks, healthcheck)
+	}
+
+	if len(healthchecks) == 0 {
+		healthchecks = append(healthchecks, appjson.Healthcheck{
+			Name:   "autogenerated",
+			Type:   checkType,
+			Uptime: 10,
+		})
+	}
+
+	return healthchecks
+}
+
+// errFailFast is the cause of cancelling the remaining checks once a check fails in fail-fast mode
+var errFailFast = errors.New("fail-fast")
+
+// checkSlots limits how many healthchecks run at once, handing a freed
+// slot to the first declared healthcheck that is waiting for one
+type checkSlots struct {
+	mu sync.Mutex
+
+	// free is the number of unused slots, or -1 when there is no limit
+	free int
+
+	// waiting holds the channels of queued healthchecks by declaration index
+	waiting map[int]chan struct{}
+}
+
+func newCheckSlots(concurrency int) *checkSlots {
+	if concurrency <= 0 {
+		concurrency = -1
+	}
+
+	return &checkSlots{free: concurrency, waiting: map[int]chan struct{}{}}
+}
+
+// wait queues the healthcheck, returning a channel that is closed once it holds a slot
+func (s *checkSlots) wait(index int) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	granted := make(chan struct{})
+	if s.free < 0 {
+		close(granted)
+		return granted
+	}
+
+	if s.free > 0 {
+		s.free--
+		close(granted)
+		return granted
+	}
+
+	s.waiting[index] = granted
+	return granted
+}
+
+// cancel removes a healthcheck from the queue, giving up its slot if it was already granted one
+func (s *checkSlots) cancel(index int) {
+	s.mu.Lock()
+	if _, ok := s.waiting[index]; ok {
+		delete(s.waiting, index)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	s.release()
+}
+
+// release frees a slot, handing it to the first declared waiting healthcheck
+func (s *checkSlots) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.free < 0 {
+		return
+	}
+
+	if len(s.waiting) == 0 {
+		s.free++
+		return
+	}
+
+	first := -1
+	for index := range s.waiting {
+		if first < 0 || index < first {
+			first = index
+		}
+	}
+
+	close(s.waiting[first])
+	delete(s.waiting, first)
+}
+
+// skippedError is reported for a healthcheck that did not run because a dependency did not pass
+type skippedError struct {
+	Dependency string
+}
+
+func (e skippedError) Error() string {
+	return fmt.Sprintf("dependency name='%s' did not pass", e.Dependency)
+}
+
 type HealthcheckResponse struct {
 	HealthcheckName string
 	Errors          []error
+	Perfdata        []appjson.Perfdata
 	Warn            bool
 }
 
-func (c *CheckCommand) processHealthcheck(healthcheck appjson.Healthcheck, container container_types.InspectResponse, logger *command.ZerologUi) HealthcheckResponse {
+// Cancelled returns whether the healthcheck was interrupted before it completed
+func (r HealthcheckResponse) Cancelled() bool {
+	if len(r.Errors) == 0 {
+		return false
+	}
+
+	return errors.Is(r.Errors[len(r.Errors)-1], appjson.ErrCancelled)
+}
+
+// Passed returns whether the healthcheck succeeded, treating a warning result as a pass
+func (r HealthcheckResponse) Passed() bool {
+	return len(r.Errors) == 0 || r.Warned()
+}
+
+// Skipped returns whether the healthcheck did not run because a dependency did not pass
+func (r HealthcheckResponse) Skipped() bool {
+	if len(r.Errors) == 0 {
+		return false
+	}
+
+	var skipped skippedError
+	return errors.As(r.Errors[len(r.Errors)-1], &skipped)
+}
+
+// TimedOut returns whether the healthcheck was still running when the check deadline passed
+func (r HealthcheckResponse) TimedOut() bool {
+	if len(r.Errors) == 0 {
+		return false
+	}
+
+	return errors.Is(r.Errors[len(r.Errors)-1], appjson.ErrTimedOut)
+}
+
+// Warned returns whether the healthcheck finished with a non-fatal warning result
+func (r HealthcheckResponse) Warned() bool {
+	if len(r.Errors) == 0 {
+		return false
+	}
+
+	return appjson.IsWarning(r.Errors[len(r.Errors)-1])
+}
+
+func (c *CheckCommand) processHealthcheck(ctx context.Context, cli *client.Client, healthcheck appjson.Healthcheck, container container_types.InspectResponse, logger *command.ZerologUi) HealthcheckResponse {
+	// an invalid healthcheck cannot be described, so it is reported before running
+	if err := healthcheck.Validate(); err != nil {
+		return HealthcheckResponse{
+			HealthcheckName: healthcheck.GetName(),
+			Errors:          []error{err},
+			Warn:            healthcheck.Warn,
+		}
+	}
+
 	tt, err := time.Parse(time.RFC3339, container.State.StartedAt)
 	if err != nil {
 		return HealthcheckResponse{
@@ -233,58 +540,85 @@ func (c *CheckCommand) processHealthcheck(healthcheck appjson.Healthcheck, conta
 		}
 	}
 
-	delay := float64(healthcheck.GetInitialDelay()) - time.Since(tt).Seconds()
+	delay := time.Duration(healthcheck.GetInitialDelay())*time.Second - time.Since(tt)
+
+	logger.Info(fmt.Sprintf("Running healthcheck %s", healthcheck.Describe(container)))
 
-	switch healthcheck.GetCheckType() {
-	case appjson.CommandCheck:
-		logger.Info(fmt.Sprintf("Running healthcheck name='%s' attempts=%d command='%s' timeout=%d type='command' wait=%d", healthcheck.GetName(), healthcheck.GetAttempts(), healthcheck.Command, healthcheck.GetTimeout(), healthcheck.GetWait()))
-	case appjson.ListeningCheck:
-		logger.Info(fmt.Sprintf("Running healthcheck name='%s' attempts=%d port=%d retries=%d timeout=%d type='listening' wait=%d", healthcheck.GetName(), healthcheck.GetAttempts(), healthcheck.Port, healthcheck.GetRetries(), healthcheck.GetTimeout(), healthcheck.GetWait()))
-	case appjson.PathCheck:
-		logger.Info(fmt.Sprintf("Running healthcheck name='%s' delay=%d path='%s' retries=%d timeout=%d type='path'", healthcheck.GetName(), healthcheck.GetInitialDelay(), healthcheck.GetPath(), healthcheck.GetRetries(), healthcheck.GetTimeout()))
-	case appjson.UptimeCheck:
-		logger.Info(fmt.Sprintf("Running healthcheck name='%s' type='uptime' uptime=%d", healthcheck.GetName(), healthcheck.Uptime))
+	if err := appjson.SleepContext(ctx, delay); err != nil {
+		return HealthcheckResponse{
+			HealthcheckName: healthcheck.GetName(),
+			Errors:          []error{appjson.ContextError(ctx)},
+			Warn:            healthcheck.Warn,
+		}
 	}
 
-	if delay > 0.0 {
-		time.Sleep(time.Duration(delay) * time.Second)
+	hctx := appjson.HealthcheckContext{
+		Headers:     c.headers,
+		IPAddress:   c.ipAddress,
+		Network:     c.networkName,
+		Port:        c.port,
+		ProcessType: c.processType,
+		Client:      cli,
+		OnAttempt: func(attempt appjson.Attempt) {
+			if attempt.Err == nil {
+				return
+			}
+
+			result := "failed"
+			if appjson.IsWarning(attempt.Err) {
+				result = "warned"
+			}
+			logger.Warn(fmt.Sprintf("Healthcheck name='%s' attempt %d/%d %s after %s (started at %s): %s", healthcheck.GetName(), attempt.Number, attempt.Attempts, result, attempt.Duration.Round(time.Millisecond), attempt.Start.Format(time.RFC3339), attempt.Err.Error()))
+		},
 	}
 
-	ctx := appjson.HealthcheckContext{
-		Headers:   c.headers,
-		IPAddress: c.ipAddress,
-		Network:   c.networkName,
-		Port:      c.port,
+	b, errs := healthcheck.Execute(ctx, container, hctx)
+	if len(errs) > 0 || c.showOutput {
+		logHealthcheckOutput(b, logger)
 	}
 
-	b, errs := healthcheck.Execute(container, ctx)
 	if len(errs) > 0 {
-		if len(b) > 0 {
-			logger.LogHeader1("Start healthcheck output")
-			for _, line := range strings.Split(string(b), "\n") {
-				line = strings.TrimSpace(line)
-				line = strings.Map(func(r rune) rune {
-					if unicode.IsPrint(r) {
-						return r
-					}
-					return -1
-				}, line)
-				if len(line) == 0 {
-					continue
-				}
-
-				logger.Info(line)
+		last := errs[len(errs)-1]
+		// hooks run under the check context, so they cannot outlast the deadline
+		if !appjson.IsWarning(last) && !errors.Is(last, appjson.ErrCancelled) && !errors.Is(last, appjson.ErrTimedOut) {
+			if err := healthcheck.HandleFailure(ctx, errs); err != nil {
+				logger.Error(fmt.Sprintf("Error in HandleFailure: %s", err))
 			}
-			logger.LogHeader1("End healthcheck output")
-		}
-		if err := healthcheck.HandleFailure(errs); err != nil {
-			logger.Error(fmt.Sprintf("Error in HandleFailure: %s", err))
 		}
 	}
 
+	perfdata := []appjson.Perfdata{}
+	if healthcheck.Format == "nagios" {
+		_, perfdata = appjson.ParseNagiosOutput(b)
+	}
+
 	return HealthcheckResponse{
 		HealthcheckName: healthcheck.GetName(),
 		Errors:          errs,
+		Perfdata:        perfdata,
 		Warn:            healthcheck.Warn,
 	}
 }
+
+func logHealthcheckOutput(b []byte, logger *command.ZerologUi) {
+	if len(b) == 0 {
+		return
+	}
+
+	logger.LogHeader1("Start healthcheck output")
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.Map(func(r rune) rune {
+			if unicode.IsPrint(r) {
+				return r
+			}
+			return -1
+		}, line)
+		if len(line) == 0 {
+			continue
+		}
+
+		logger.Info(line)
+	}
+	logger.LogHeader1("End healthcheck output")
+}
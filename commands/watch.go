@@ -0,0 +1,339 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	container_types "github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+
+	"docker-container-healthchecker/appjson"
+)
+
+type WatchCommand struct {
+	command.Meta
+
+	appJSONFile string
+	headers     []string
+	checkType   string
+	interval    int
+	ipAddress   string
+	networkName string
+	port        int
+	processType string
+	showOutput  bool
+}
+
+func (c *WatchCommand) Name() string {
+	return "watch"
+}
+
+func (c *WatchCommand) Synopsis() string {
+	return "Continuously checks the health status of a container"
+}
+
+func (c *WatchCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+func (c *WatchCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Watch the liveness of the web process":  fmt.Sprintf("%s %s dokku.web.1", appName, c.Name()),
+		"Watch the readiness of the web process": fmt.Sprintf("%s %s dokku.web.1 --type readiness", appName, c.Name()),
+	}
+}
+
+func (c *WatchCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "container-id",
+		Description: "ID or Name of container to watch",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+func (c *WatchCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *WatchCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+func (c *WatchCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	f.IntVar(&c.port, "port", 5000, "container port to check")
+	f.IntVar(&c.interval, "interval", 0, "seconds between probes, overriding the wait of every healthcheck")
+	f.StringSliceVar(&c.headers, "header", []string{}, "one or more headers in 'curl -H' format to specify for path requests")
+	f.StringVar(&c.appJSONFile, "app-json", "app.json", "full path to app.json file")
+	f.StringVar(&c.checkType, "type", "liveness", "check to interpret")
+	f.StringVar(&c.ipAddress, "ip-address", "", "an ip address to use for http 'path' checks")
+	f.StringVar(&c.networkName, "network", "bridge", "container network to use for http 'path' checks")
+	f.StringVar(&c.processType, "process-type", "web", "process type to check")
+	f.BoolVar(&c.showOutput, "show-output", false, "show healthcheck output for successful probes")
+	return f
+}
+
+func (c *WatchCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		complete.Flags{
+			"--app-json":     complete.PredictAnything,
+			"--header":       complete.PredictAnything,
+			"--interval":     complete.PredictAnything,
+			"--ip-address":   complete.PredictAnything,
+			"--network":      complete.PredictAnything,
+			"--port":         complete.PredictAnything,
+			"--process-type": complete.PredictAnything,
+			"--show-output":  complete.PredictNothing,
+			"--type":         complete.PredictSet("liveness", "readiness", "startup"),
+		},
+	)
+}
+
+func (c *WatchCommand) Run(args []string) int {
+	flags := c.FlagSet()
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		c.Ui.Error(err.Error())
+		c.Ui.Error(command.CommandErrorText(c))
+		return 1
+	}
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		c.Ui.Error(err.Error())
+		c.Ui.Error(command.CommandErrorText(c))
+		return 1
+	}
+
+	logger, ok := c.Ui.(*command.ZerologUi)
+	if !ok {
+		c.Ui.Error("Unable to fetch logger from cli")
+		return 1
+	}
+
+	if c.interval < 0 {
+		logger.Error("Invalid interval: must be zero or greater")
+		return 1
+	}
+
+	// watching only stops once interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	appJSON, err := readAppJSON(c.appJSONFile)
+	if err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+
+	containerIDorName := arguments["container-id"].StringValue()
+	cli, err := client.NewClientWithOpts(
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+	defer cli.Close()
+
+	inspect, err := cli.ContainerInspect(ctx, containerIDorName, client.ContainerInspectOptions{})
+	if err != nil {
+		logger.Error(err.Error())
+		return 1
+	}
+	container := inspect.Container
+
+	if !container.State.Running {
+		logger.Error(fmt.Sprintf("Container state: %s", container.State.Status))
+		return 1
+	}
+
+	healthchecks := collectHealthchecks(appJSON, c.processType, c.checkType, c.port, logger)
+	for _, healthcheck := range healthchecks {
+		if err := healthcheck.Validate(); err != nil {
+			logger.Error(fmt.Sprintf("Invalid healthcheck name='%s': %s", healthcheck.GetName(), err.Error()))
+			return 1
+		}
+	}
+
+	header := fmt.Sprintf("Watching %d healthchecks (interval: ", len(healthchecks))
+	if c.interval > 0 {
+		header += fmt.Sprintf("%s)", time.Duration(c.interval)*time.Second)
+	} else {
+		header += "per-healthcheck wait)"
+	}
+	logger.LogHeader2(header)
+
+	var wg sync.WaitGroup
+	states := make([]watchStatus, len(healthchecks))
+	for i, healthcheck := range healthchecks {
+		wg.Add(1)
+		go func(i int, h appjson.Healthcheck) {
+			defer wg.Done()
+			states[i] = c.watchHealthcheck(ctx, cli, h, container, logger)
+		}(i, healthcheck)
+	}
+	wg.Wait()
+
+	errorCount := 0
+	for i, healthcheck := range healthchecks {
+		if states[i] == watchUnhealthy && !healthcheck.Warn {
+			errorCount++
+		}
+	}
+
+	return errorCount
+}
+
+// watchHealthcheck probes the healthcheck until the context is done,
+// returning the state it was last in
+func (c *WatchCommand) watchHealthcheck(ctx context.Context, cli *client.Client, healthcheck appjson.Healthcheck, container container_types.InspectResponse, logger *command.ZerologUi) watchStatus {
+	logger.Info(fmt.Sprintf("Watching healthcheck %s", healthcheck.Describe(container)))
+
+	tt, err := time.Parse(time.RFC3339, container.State.StartedAt)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failure in name='%s': %s", healthcheck.GetName(), err.Error()))
+		return watchUnknown
+	}
+
+	// the initial delay only applies to the first probe
+	delay := time.Duration(healthcheck.GetInitialDelay())*time.Second - time.Since(tt)
+	if appjson.SleepContext(ctx, delay) != nil {
+		return watchUnknown
+	}
+
+	// every probe is a single attempt, with the thresholds applied
+	// across probes rather than within one
+	probe := healthcheck
+	probe.Attempts = 1
+	probe.FailureThreshold = 0
+	probe.SuccessThreshold = 0
+
+	interval := time.Duration(healthcheck.GetWait()) * time.Second
+	if c.interval > 0 {
+		interval = time.Duration(c.interval) * time.Second
+	}
+
+	hctx := appjson.HealthcheckContext{
+		Headers:     c.headers,
+		IPAddress:   c.ipAddress,
+		Network:     c.networkName,
+		Port:        c.port,
+		ProcessType: c.processType,
+		Client:      cli,
+	}
+
+	state := newWatchState(healthcheck.GetSuccessThreshold(), healthcheck.GetFailureThreshold())
+	for {
+		b, errs := c.probeHealthcheck(ctx, cli, probe, container.ID, hctx)
+		if ctx.Err() != nil {
+			return state.status
+		}
+
+		passed := len(errs) == 0 || appjson.IsWarning(errs[len(errs)-1])
+		if !passed || c.showOutput {
+			logHealthcheckOutput(b, logger)
+		}
+
+		previous := state.status
+		changed := state.record(passed)
+		if !passed {
+			err := errs[len(errs)-1]
+			logger.Warn(fmt.Sprintf("Healthcheck name='%s' probe failed (consecutive failures: %d): %s", healthcheck.GetName(), state.failures, err.Error()))
+		}
+
+		if changed {
+			if state.status == watchHealthy {
+				logger.Info(fmt.Sprintf("Healthcheck name='%s' transitioned from %s to %s", healthcheck.GetName(), previous, state.status))
+			} else {
+				err := errs[len(errs)-1]
+				logger.Error(fmt.Sprintf("Healthcheck name='%s' transitioned from %s to %s: %s", healthcheck.GetName(), previous, state.status, err.Error()))
+				if err := healthcheck.HandleFailure(ctx, errs); err != nil {
+					logger.Error(fmt.Sprintf("Error in HandleFailure: %s", err))
+				}
+			}
+		}
+
+		if appjson.SleepContext(ctx, interval) != nil {
+			return state.status
+		}
+	}
+}
+
+// probeHealthcheck runs a single attempt of the healthcheck against the current state of the container
+func (c *WatchCommand) probeHealthcheck(ctx context.Context, cli *client.Client, probe appjson.Healthcheck, containerID string, hctx appjson.HealthcheckContext) ([]byte, []error) {
+	inspect, err := cli.ContainerInspect(ctx, containerID, client.ContainerInspectOptions{})
+	if err != nil {
+		return []byte{}, []error{err}
+	}
+
+	return probe.Execute(ctx, inspect.Container, hctx)
+}
+
+// watchStatus is the health of a watched healthcheck
+type watchStatus string
+
+const (
+	watchUnknown   watchStatus = "unknown"
+	watchHealthy   watchStatus = "healthy"
+	watchUnhealthy watchStatus = "unhealthy"
+)
+
+// watchState debounces probe results, only changing status once enough
+// consecutive probes agree
+type watchState struct {
+	status watchStatus
+
+	successes int
+	failures  int
+
+	successThreshold int
+	failureThreshold int
+}
+
+func newWatchState(successThreshold int, failureThreshold int) *watchState {
+	return &watchState{
+		status:           watchUnknown,
+		successThreshold: successThreshold,
+		failureThreshold: failureThreshold,
+	}
+}
+
+// record adds a probe result, returning whether the status changed
+func (s *watchState) record(passed bool) bool {
+	next := s.status
+	if passed {
+		s.failures = 0
+		s.successes++
+		if s.successes >= s.successThreshold {
+			next = watchHealthy
+		}
+	} else {
+		s.successes = 0
+		s.failures++
+		if s.failures >= s.failureThreshold {
+			next = watchUnhealthy
+		}
+	}
+
+	if next == s.status {
+		return false
+	}
+
+	s.status = next
+	return true
+}
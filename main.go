@@ -68,6 +68,9 @@ func Commands(ctx context.Context, meta command.Meta) map[string]cli.CommandFact
 		"exists": func() (cli.Command, error) {
 			return &commands.ExistsCommand{Meta: meta}, nil
 		},
+		"watch": func() (cli.Command, error) {
+			return &commands.WatchCommand{Meta: meta}, nil
+		},
 		"version": func() (cli.Command, error) {
 			return &command.VersionCommand{Meta: meta}, nil
 		},
@@ -266,6 +266,17 @@ teardown() {
   assert_output_contains "Running healthcheck name='command check' attempts=3 command='\[echo hi\]' timeout=5 type='command' wait=5"
 }
 
+@test "[check] command check show-output" {
+  echo '{"healthchecks":{"web":[{"command":["echo","hello from the container"],"name":"command check","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1 --show-output
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Healthcheck succeeded name='command check'"
+  assert_output_contains "hello from the container" 2
+}
+
 @test "[check] command check-error" {
   echo '{"healthchecks":{"web":[{"attempts":1,"command":["python3","-c","import sys; print(\"This is an error\"); sys.exit(1)"],"name":"command check","type":"startup","wait":0}]}}' >app.json
 
@@ -274,9 +285,183 @@ teardown() {
   echo "status: $status"
   assert_failure
   assert_output_contains "This is an error" 2
+  assert_output_contains "Healthcheck name='command check' attempt 1/1 failed after"
   assert_output_contains "Failure in name='command check': non-zero exit code 1"
 }
 
+@test "[check] command check warn exit code" {
+  echo '{"healthchecks":{"web":[{"attempts":1,"command":["python3","-c","import sys; sys.exit(1)"],"name":"command check","type":"startup","wait":0,"warnExitCodes":[1]}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "successExitCodes=\[0\] warnExitCodes=\[1\]"
+  assert_output_contains "Warning in name='command check': exit code 1 mapped to warning"
+}
+
+@test "[check] command check nagios format" {
+  echo '{"healthchecks":{"web":[{"attempts":1,"command":["python3","-c","import sys; print(\"LOAD WARNING - load average: 6.00 | load1=6.000;5;10;0\"); sys.exit(1)"],"format":"nagios","name":"command check","type":"startup","wait":0}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Warning in name='command check': nagios state WARNING: LOAD WARNING - load average: 6.00"
+  assert_output_contains "Perfdata name='command check' label='load1' value=6 warning='5' critical='10' min='0' max=''"
+}
+
+@test "[check] hostCommand check" {
+  echo '{"healthchecks":{"web":[{"hostCommand":["sh","-c","echo \"checking $HEALTHCHECK_CONTAINER_NAME on port $HEALTHCHECK_PORT\""],"name":"host command check","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1 --show-output
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Healthcheck succeeded name='host command check'"
+  assert_output_contains "checking dch-test-1 on port 5000"
+}
+
+@test "[check] deadline" {
+  echo '{"healthchecks":{"web":[{"attempts":1,"hostCommand":["sleep","30"],"name":"slow check","timeout":60,"type":"startup"}]},"healthcheckSettings":{"web":{"deadline":2}}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Executing 1 healthchecks (worst-case duration: 1m0s, deadline: 2s)"
+  assert_output_contains "Timed out name='slow check'"
+
+  run "$BIN_NAME" check dch-test-1 --deadline 1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "deadline: 1s)"
+}
+
+@test "[check] fail-fast" {
+  echo '{"healthchecks":{"web":[{"attempts":1,"hostCommand":["false"],"name":"broken check","type":"startup"},{"name":"slow check","type":"startup","uptime":60}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1 --fail-fast
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Failure in name='broken check': non-zero exit code 1"
+  assert_output_contains "Cancelled name='slow check': fail-fast: name='broken check' failed"
+
+  echo '{"healthchecks":{"web":[{"attempts":1,"hostCommand":["false"],"name":"broken check","type":"startup"},{"dependsOn":["broken check"],"hostCommand":["true"],"name":"dependent check","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1 --fail-fast
+  echo "output: $output"
+  echo "status: $status"
+  assert_equal "$status" 1
+  assert_output_contains "Cancelled name='dependent check': fail-fast: name='broken check' failed"
+  assert_output_contains "Skipped name='dependent check'" 0
+}
+
+@test "[check] concurrency" {
+  echo '{"healthchecks":{"web":[{"attempts":1,"hostCommand":["sh","-c","sleep 1; echo first finished"],"name":"first","type":"startup"},{"attempts":1,"hostCommand":["true"],"name":"second","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1 --concurrency 1 --show-output
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "concurrency: 1)"
+  [[ "$output" == *"first finished"*"Running healthcheck name='second'"* ]] || flunk "expected second check to run after first"
+}
+
+@test "[check] dependsOn" {
+  echo '{"healthchecks":{"web":[{"attempts":1,"hostCommand":["false"],"name":"shallow","type":"startup"},{"dependsOn":["shallow"],"hostCommand":["true"],"name":"deep","type":"startup"},{"hostCommand":["true"],"name":"independent","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Healthcheck succeeded name='independent'"
+  assert_output_contains "Skipped name='deep': dependency name='shallow' did not pass"
+  assert_output_contains "Running healthcheck name='deep'" 0
+}
+
+@test "[check] dependsOn cycle" {
+  echo '{"healthchecks":{"web":[{"dependsOn":["deep"],"hostCommand":["true"],"name":"shallow","type":"startup"},{"dependsOn":["shallow"],"hostCommand":["true"],"name":"deep","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "healthcheck dependency cycle detected: shallow -> deep -> shallow"
+}
+
+@test "[watch] transitions" {
+  touch "$BATS_TEST_TMPDIR/healthy"
+  echo '{"healthchecks":{"web":[{"failureThreshold":2,"hostCommand":["test","-f","'"$BATS_TEST_TMPDIR/healthy"'"],"name":"flag check","type":"liveness","wait":1}]}}' >app.json
+
+  (sleep 3 && rm -f "$BATS_TEST_TMPDIR/healthy") &
+  run timeout --preserve-status -s INT 8 "$BIN_NAME" watch dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Watching 1 healthchecks (interval: per-healthcheck wait)"
+  assert_output_contains "Healthcheck name='flag check' transitioned from unknown to healthy"
+  assert_output_contains "Healthcheck name='flag check' probe failed (consecutive failures: 1): non-zero exit code 1"
+  assert_output_contains "Healthcheck name='flag check' transitioned from healthy to unhealthy: non-zero exit code 1"
+  assert_output_contains "Healthcheck name='flag check' transitioned from unknown to unhealthy" 0
+}
+
+@test "[check] dockerHealth check without HEALTHCHECK" {
+  echo '{"healthchecks":{"web":[{"dockerHealth":true,"name":"docker health check","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Failure in name='docker health check': container does not define a docker HEALTHCHECK"
+  assert_output_contains "Running healthcheck name='docker health check' attempts=3 timeout=5 type='dockerHealth' wait=5"
+}
+
+@test "[check] logs check fail pattern" {
+  echo '{"healthchecks":{"web":[{"logs":{"readyPattern":"this-will-never-be-logged","failPatterns":[".+"]},"name":"logs check","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Running healthcheck name='logs check' attempts=3 readyPattern='this-will-never-be-logged' timeout=5 type='logs' wait=5"
+}
+
+@test "[check] process check error" {
+  echo '{"healthchecks":{"web":[{"attempts":1,"name":"process check","processes":[{"name":"this-process-does-not-exist"}],"type":"startup","wait":0}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Failure in name='process check': expected at least 1 processes matching name='this-process-does-not-exist', found 0"
+}
+
+@test "[check] file check" {
+  echo '{"healthchecks":{"web":[{"files":[{"path":"/etc/passwd","content":"root"},{"path":"/this-path-does-not-exist","exists":false}],"name":"file check","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_success
+  assert_output_contains "Healthcheck succeeded name='file check'"
+  assert_output_contains "Running healthcheck name='file check' attempts=3 files=2 timeout=5 type='file' wait=5"
+}
+
+@test "[check] env check error" {
+  echo '{"healthchecks":{"web":[{"envAssertions":[{"name":"PATH","absent":true}],"name":"env check","type":"startup"}]}}' >app.json
+
+  run "$BIN_NAME" check dch-test-1
+  echo "output: $output"
+  echo "status: $status"
+  assert_failure
+  assert_output_contains "Failure in name='env check': environment variable 'PATH' is set"
+  assert_output_contains "PATH=<redacted>"
+  assert_output_contains "Running healthcheck name='env check' envAssertions=1 type='env'"
+}
+
 @test "[convert] checks-root" {
   run "$BIN_NAME" convert tests/fixtures/checks-root.CHECKS
   echo "output: $output"